@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gradienthealth/dicom/dicomtag"
+	"github.com/macadamian/dicom"
 	"github.com/macadamian/dicom/dicom2019bdata"
 	"os"
 	"strconv"
@@ -75,10 +76,57 @@ type TagAudit struct {
 type TagDef struct {
 	Keyword    string
 	VR         []string
-	VM         string
+	VM         dicom.VM
 	Deidentify string
 }
 
+// The Go types need concrete tags, so the repeating groups (e.g. "(60xx,3000)") are expanded into
+// a tag definition and tag usage for each of the groups
+func expandPatterns(sch *SchemaDef) {
+	for key, td := range sch.TagDefs {
+		p, err := dicom.ParseTagPattern(key)
+		if err != nil || !p.IsRepeating() {
+			continue
+		}
+		delete(sch.TagDefs, key)
+		for _, t := range p.Tags() {
+			sch.TagDefs[t.String()] = td
+		}
+	}
+
+	for name, md := range sch.ModuleDefs {
+		tags := []TagUsage{}
+		for _, tgu := range md.Tags {
+			paths := [][]string{{}}
+			for _, tgs := range tgu.Path {
+				expanded := []string{tgs}
+				if p, err := dicom.ParseTagPattern(tgs); err == nil && p.IsRepeating() {
+					expanded = []string{}
+					for _, t := range p.Tags() {
+						expanded = append(expanded, t.String())
+					}
+				}
+
+				next := [][]string{}
+				for _, path := range paths {
+					for _, e := range expanded {
+						next = append(next, append(append([]string{}, path...), e))
+					}
+				}
+				paths = next
+			}
+
+			for _, path := range paths {
+				etgu := tgu
+				etgu.Path = path
+				tags = append(tags, etgu)
+			}
+		}
+		md.Tags = tags
+		sch.ModuleDefs[name] = md
+	}
+}
+
 func main() {
 	sch := SchemaDef{}
 
@@ -87,6 +135,8 @@ func main() {
 		panic(err)
 	}
 
+	expandPatterns(&sch)
+
 	out := os.Stdout
 
 	// TODO this should be declared in a separate file
@@ -349,6 +399,11 @@ function SOPClassUID(sopClassUIDString: string) {
 				typ = "string"
 			}
 
+			// Decimal and integer strings are numbers in the Go structs
+			if td.VR[0] == "DS" || td.VR[0] == "IS" {
+				typ = "number"
+			}
+
 			baseType := typ
 			if baseType == "string" {
 				baseType = "String"
@@ -357,10 +412,8 @@ function SOPClassUID(sopClassUIDString: string) {
 			}
 
 			// Any range or sequence just translated into an array
-			if strings.Contains(td.VM, "-") || td.VR[0] == "SQ" {
+			if td.VM.Multiple() || td.VR[0] == "SQ" {
 				typ = "Array<" + typ + ">"
-			} else if _, err := strconv.Atoi(td.VM); err == nil && td.VM != "1" {
-				typ = fmt.Sprintf("Array<%s>", typ)
 			}
 
 			initialValue := ""
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gradienthealth/dicom/dicomtag"
+	"github.com/macadamian/dicom"
 	"github.com/macadamian/dicom/dicom2019bdata"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
@@ -37,6 +39,34 @@ func fixKeyword(kw string) string {
 	return string(r)
 }
 
+// Add the terms that aren't already in the list
+func mergeTerms(terms []dicom.Term, more []dicom.Term) []dicom.Term {
+	for _, m := range more {
+		found := false
+		for _, t := range terms {
+			if t.Value == m.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			terms = append(terms, m)
+		}
+	}
+	return terms
+}
+
+// Name of a constant for a term of an enumeration type
+func termConstName(typ string, value string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, value)
+	return typ + strings.Trim(name, "_")
+}
+
 ////  Schema (with some additions)
 
 type SchemaDef struct {
@@ -63,9 +93,18 @@ type ModuleDef struct {
 type TagUsage struct {
 	Path []string
 	Type string
+	EnumeratedValues []dicom.Term
+	DefinedTerms []dicom.Term
 	Audit []TagAudit
 }
 
+// An enumeration type for the values of an attribute
+type EnumDef struct {
+	Keyword    string
+	Enumerated bool
+	Terms      []dicom.Term
+}
+
 type TagAudit struct {
 	Name string
 	Type string
@@ -75,10 +114,80 @@ type TagAudit struct {
 type TagDef struct {
 	Keyword    string
 	VR         []string
-	VM         string
+	VM         dicom.VM
 	Deidentify string
 }
 
+// The Go types need concrete tags, so the repeating groups (e.g. "(60xx,3000)") are expanded into
+// a tag definition and tag usage for each of the groups
+func expandPatterns(sch *SchemaDef) {
+	for key, td := range sch.TagDefs {
+		p, err := dicom.ParseTagPattern(key)
+		if err != nil || !p.IsRepeating() {
+			continue
+		}
+		delete(sch.TagDefs, key)
+		for _, t := range p.Tags() {
+			sch.TagDefs[t.String()] = td
+		}
+	}
+
+	for name, md := range sch.ModuleDefs {
+		tags := []TagUsage{}
+		for _, tgu := range md.Tags {
+			paths := [][]string{{}}
+			for _, tgs := range tgu.Path {
+				expanded := []string{tgs}
+				if p, err := dicom.ParseTagPattern(tgs); err == nil && p.IsRepeating() {
+					expanded = []string{}
+					for _, t := range p.Tags() {
+						expanded = append(expanded, t.String())
+					}
+				}
+
+				next := [][]string{}
+				for _, path := range paths {
+					for _, e := range expanded {
+						next = append(next, append(append([]string{}, path...), e))
+					}
+				}
+				paths = next
+			}
+
+			for _, path := range paths {
+				etgu := tgu
+				etgu.Path = path
+				tags = append(tags, etgu)
+			}
+		}
+		md.Tags = tags
+		sch.ModuleDefs[name] = md
+	}
+}
+
+// The name of a Go type from the name of a class, module or sequence
+func typeName(name string) string {
+	name = strings.Replace(name, " ", "", -1)
+	name = strings.Replace(name, "-", "", -1)
+	name = strings.Replace(name, "/", "", -1)
+	if !unicode.IsLetter([]rune(name)[0]) {
+		name = "A" + name
+	}
+	return name
+}
+
+// Whether any of the attributes of the modules have Enumerated Values or Defined Terms
+func hasTerms(sch *SchemaDef) bool {
+	for _, md := range sch.ModuleDefs {
+		for _, tgu := range md.Tags {
+			if len(tgu.EnumeratedValues) > 0 || len(tgu.DefinedTerms) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func main() {
 	sch := SchemaDef{}
 
@@ -87,6 +196,8 @@ func main() {
 		panic(err)
 	}
 
+	expandPatterns(&sch)
+
 	out, err := os.Create("../dicom2019b/dicom-2019b-pkg.go")
 	if err != nil {
 		panic(err)
@@ -96,33 +207,33 @@ func main() {
 	fmt.Fprintf(out, "package dicom2019b\n\n")
 
 	fmt.Fprintf(out, "import \"github.com/gradienthealth/dicom\"\n")
-	fmt.Fprintf(out, "import \"github.com/gradienthealth/dicom/dicomtag\"\n\n")
+	fmt.Fprintf(out, "import \"github.com/gradienthealth/dicom/dicomtag\"\n")
+	fmt.Fprintf(out, "import \"github.com/macadamian/dicom/dicomvalue\"\n\n")
 
 	fmt.Fprintf(out, "// TODO embedded spec references as godocs\n")
-	fmt.Fprintf(out, "// TODO multiplicities for sequences\n")
-	fmt.Fprintf(out, "// TODO enumeration types for enumerated values\n\n")
+	if hasTerms(&sch) {
+		fmt.Fprintf(out, "// TODO multiplicities for sequences\n\n")
+	} else {
+		// Schemas crawled before the terms were recorded don't have any to generate
+		fmt.Fprintf(out, "// TODO multiplicities for sequences\n")
+		fmt.Fprintf(out, "// TODO enumeration types for enumerated values\n\n")
+	}
+
+	// Storage class struct names by their SOP Class UID, in the order of the class definitions
+	storageClasses := [][2]string{}
+	typeNames := map[string]bool{}
 
 	for _, cd := range sch.ClassDefs {
-		name := cd.Name
-		name = strings.Replace(name, " ", "", -1)
-		name = strings.Replace(name, "-", "", -1)
-		name = strings.Replace(name, "/", "", -1)
-		if !unicode.IsLetter([]rune(name)[0]) {
-			name = "A" + name
-		}
+		name := typeName(cd.Name)
+
+		storageClasses = append(storageClasses, [2]string{cd.SOPClassUid, name})
+		typeNames[name] = true
 
 		fmt.Fprintf(out, "type %s struct {\n", name)
 		fmt.Fprintf(out, "\tSOPClassUID bool `%s`\n", cd.SOPClassUid)
 
 		for _, mdu := range cd.Modules {
-			name = mdu.Name
-			name = strings.Replace(name, " ", "", -1)
-			name = strings.Replace(name, "-", "", -1)
-			name = strings.Replace(name, "/", "", -1)
-
-			if !unicode.IsLetter([]rune(name)[0]) {
-				name = "A" + name
-			}
+			name = typeName(mdu.Name)
 
 			typ := name
 
@@ -144,12 +255,7 @@ func main() {
 	// First, do a scan to divide up the tags into their structs
 	structdefs := map[string]*ModuleDef{}
 	for name, md := range sch.ModuleDefs {
-		name = strings.Replace(name, " ", "", -1)
-		name = strings.Replace(name, "-", "", -1)
-		name = strings.Replace(name, "/", "", -1)
-		if !unicode.IsLetter([]rune(name)[0]) {
-			name = "A" + name
-		}
+		name = typeName(name)
 
 		sd := &ModuleDef{}
 		structdefs[name] = sd
@@ -184,6 +290,9 @@ func main() {
 								t.Type = "3"
 							}
 
+							t.EnumeratedValues = mergeTerms(t.EnumeratedValues, tgu.EnumeratedValues)
+							t.DefinedTerms = mergeTerms(t.DefinedTerms, tgu.DefinedTerms)
+
 							parentstruct.Tags[idx] = t
 
 							break
@@ -191,7 +300,7 @@ func main() {
 					}
 
 					if !found {
-						newt := TagUsage{Path: []string{tgs}, Type: tgu.Type, Audit: []TagAudit{TagAudit{name, tgu.Type, strings.Join(tgu.Path[:len(tgu.Path)-1], ",")}}}
+						newt := TagUsage{Path: []string{tgs}, Type: tgu.Type, EnumeratedValues: tgu.EnumeratedValues, DefinedTerms: tgu.DefinedTerms, Audit: []TagAudit{TagAudit{name, tgu.Type, strings.Join(tgu.Path[:len(tgu.Path)-1], ",")}}}
 						parentstruct.Tags = append(parentstruct.Tags, newt)
 					}
 				}
@@ -208,13 +317,15 @@ func main() {
 		}
 	}
 
+	for name := range structdefs {
+		typeNames[typeName(name)] = true
+	}
+
+	// Enumeration types by their name
+	enumdefs := map[string]*EnumDef{}
+
 	for name, md := range structdefs {
-		name = strings.Replace(name, " ", "", -1)
-		name = strings.Replace(name, "-", "", -1)
-		name = strings.Replace(name, "/", "", -1)
-		if !unicode.IsLetter([]rune(name)[0]) {
-			name = "A" + name
-		}
+		name = typeName(name)
 
 		fieldNames := map[string]bool{}
 
@@ -327,14 +438,47 @@ func main() {
 				typ = "dicom.PixelDataInfo"
 			}
 
-			// Any range or sequence just translated into a slice
-			if strings.Contains(td.VM, "-") || td.VR[0] == "SQ" {
-				typ = "[]" + typ
+			// String VRs with a structured value get a richer type
+			switch td.VR[0] {
+			case "DA":
+				typ = "dicomvalue.Date"
+			case "TM":
+				typ = "dicomvalue.Time"
+			case "DT":
+				typ = "dicomvalue.DateTime"
+			case "PN":
+				typ = "dicomvalue.PersonName"
+			case "AS":
+				typ = "dicomvalue.Age"
+			case "DS":
+				typ = "float64"
+			case "IS":
+				typ = "int64"
 			}
 
-			// There could be a specific number of required values
-			if _, err := strconv.Atoi(td.VM); err == nil && td.VM != "1" {
-				typ = fmt.Sprintf("[%s]%s", td.VM, typ)
+			// Attributes with Enumerated Values or Defined Terms get a named type with constants for the
+			//  terms from all of the modules where the attribute is used
+			if typ == "string" && (len(tgu.EnumeratedValues) > 0 || len(tgu.DefinedTerms) > 0) {
+				typ = typeName(fixKeyword(td.Keyword))
+				for typeNames[typ] {
+					typ = typ + "Value"
+				}
+
+				ed, ok := enumdefs[typ]
+				if !ok {
+					ed = &EnumDef{Keyword: td.Keyword}
+					enumdefs[typ] = ed
+				}
+				ed.Enumerated = ed.Enumerated || len(tgu.EnumeratedValues) > 0
+				ed.Terms = mergeTerms(mergeTerms(ed.Terms, tgu.EnumeratedValues), tgu.DefinedTerms)
+			}
+
+			if n, fixed := td.VM.Fixed(); fixed && n > 1 && td.VR[0] != "SQ" {
+				// There could be a specific number of required values
+				typ = fmt.Sprintf("[%d]%s", n, typ)
+			} else if td.VM.Multiple() || td.VR[0] == "SQ" {
+				// Any range or sequence just translated into a slice
+				typ = "[]" + typ
 			}
 
 			// Optional types that aren't already slices are made into pointer types
@@ -363,4 +507,47 @@ func main() {
 		}
 		fmt.Fprintf(out, "}\n\n")
 	}
+
+	enumNames := []string{}
+	for name := range enumdefs {
+		enumNames = append(enumNames, name)
+	}
+	sort.Strings(enumNames)
+
+	for _, name := range enumNames {
+		ed := enumdefs[name]
+		sort.SliceStable(ed.Terms, func(i, j int) bool { return ed.Terms[i].Value < ed.Terms[j].Value })
+
+		if ed.Enumerated {
+			fmt.Fprintf(out, "// %s has the Enumerated Values of %s\n", name, ed.Keyword)
+		} else {
+			fmt.Fprintf(out, "// %s has the Defined Terms of %s, other values may be used\n", name, ed.Keyword)
+		}
+		fmt.Fprintf(out, "type %s string\n\n", name)
+
+		fmt.Fprintf(out, "const (\n")
+		constNames := map[string]bool{}
+		for _, t := range ed.Terms {
+			cname := termConstName(name, t.Value)
+			for counter := 1; constNames[cname]; counter++ {
+				cname = fmt.Sprintf("%s%d", termConstName(name, t.Value), counter)
+			}
+			constNames[cname] = true
+
+			fmt.Fprintf(out, "\t%s %s = %q", cname, name, t.Value)
+			if t.Meaning != "" {
+				fmt.Fprintf(out, " // %s", t.Meaning)
+			}
+			fmt.Fprintf(out, "\n")
+		}
+		fmt.Fprintf(out, ")\n\n")
+	}
+
+	fmt.Fprintf(out, "// StorageClasses maps the SOP Class UID of each storage class to a constructor of a new\n")
+	fmt.Fprintf(out, "// value of its type that can be unmarshaled into.\n")
+	fmt.Fprintf(out, "var StorageClasses = map[string]func() interface{}{\n")
+	for _, sc := range storageClasses {
+		fmt.Fprintf(out, "\t\"%s\": func() interface{} { return &%s{} },\n", sc[0], sc[1])
+	}
+	fmt.Fprintf(out, "}\n")
 }
@@ -4,9 +4,10 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
-	"github.com/gradienthealth/dicom/dicomtag"
+	"html"
 	"net/http"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -19,6 +20,9 @@ type SchemaDef struct {
 	ClassDefs  []*ClassDef
 	TagDefs    map[string]*TagDef
 	ModuleDefs map[string]*ModuleDef
+	UIDs       map[string]*UIDDef    `json:",omitempty"`
+	FileMeta   []TagUsage            `json:",omitempty"`
+	Commands   map[string][]TagUsage `json:",omitempty"`
 }
 
 type ClassDef struct {
@@ -37,8 +41,16 @@ type ModuleDef struct {
 }
 
 type TagUsage struct {
-	Path []string
-	Type string
+	Path             []string
+	Type             string
+	EnumeratedValues []Term `json:",omitempty"`
+	DefinedTerms     []Term `json:",omitempty"`
+}
+
+type Term struct {
+	Value       string
+	Meaning     string `json:",omitempty"`
+	ValueNumber int    `json:",omitempty"`
 }
 
 type TagDef struct {
@@ -48,6 +60,13 @@ type TagDef struct {
 	Deidentify string
 }
 
+type UIDDef struct {
+	Name    string
+	Keyword string `json:",omitempty"`
+	Type    string
+	Retired bool `json:",omitempty"`
+}
+
 //// XML Parsing
 
 type Node struct {
@@ -265,18 +284,20 @@ func extractDicom(version string) {
 									return true
 								}
 
-								tg, tp := n.Nodes[1], n.Nodes[2]
+								tg, tp, desc := n.Nodes[1], n.Nodes[2], n.Nodes[3]
 
 								tn := tg.Nodes[0]
 								if len(tn.Nodes) != 0 {
 									tn = tn.Nodes[0]
 								}
 
-								for _, t := range parseTagPattern(tn.Content) {
+								enums, defined := extractTerms(desc, part)
+
+								if t, ok := parseTagPattern(tn.Content); ok {
 									if len(parents) <= level {
-										parents = append(parents, t.String())
+										parents = append(parents, t)
 									} else {
-										parents[level] = t.String()
+										parents[level] = t
 
 										// Potentially resize the parents slice
 										parents = parents[:level+1]
@@ -285,6 +306,8 @@ func extractDicom(version string) {
 									tdef := TagUsage{}
 									tdef.Type = tp.Nodes[0].Content
 									tdef.Path = append([]string{}, parents...)
+									tdef.EnumeratedValues = enums
+									tdef.DefinedTerms = defined
 									mdldef.Tags = append(mdldef.Tags, tdef)
 								}
 							}
@@ -346,7 +369,7 @@ func extractDicom(version string) {
 
 			tagdef := TagDef{}
 
-			for _, t := range parseTagPattern(tagstr) {
+			if t, ok := parseTagPattern(tagstr); ok {
 				tagdef.Keyword = keywordstr
 				tagdef.VR = []string{}
 
@@ -355,13 +378,35 @@ func extractDicom(version string) {
 				}
 
 				tagdef.VM = vmstr
-				tagdefs[t.String()] = &tagdef
+				tagdefs[t] = &tagdef
 			}
 		}
 
 		return true
 	})
 
+	// The file meta elements (group 0002) have their own registry in PS3.6 and the command elements
+	// (group 0000) are in the command dictionary of PS3.7
+	registries := []*Node{part6.Dict["table_7-1"], nil}
+	commands := map[string][]TagUsage{}
+	if part7 := linkLookup["PS3.7"]; part7 != nil {
+		registries[1] = part7.Dict["table_E.1-1"]
+		commands = parseCommands(part7)
+	}
+	for _, tbl := range registries {
+		if tbl == nil {
+			continue
+		}
+		for t, tagdef := range parseElementRegistry(tbl) {
+			tagdefs[t] = tagdef
+		}
+	}
+
+	fileMeta := []TagUsage{}
+	if part10 := linkLookup["PS3.10"]; part10 != nil && part10.Dict["table_7.1-1"] != nil {
+		fileMeta = parseFileMeta(part10.Dict["table_7.1-1"])
+	}
+
 	part15 := linkLookup["PS3.15"]
 	confidProfileAttrTbl := part15.Dict["table_E.1-1"]
 
@@ -391,10 +436,10 @@ func extractDicom(version string) {
 			walkNode([]Node{basicprof}, getVal)
 			basicprofstr := v
 
-			for _, t := range parseTagPattern(tagstr) {
-				td := tagdefs[t.String()]
+			if t, ok := parseTagPattern(tagstr); ok {
+				td := tagdefs[t]
 				if td == nil {
-					// Likely this is a retired tag, just skip
+					// Not in the data dictionary (e.g. a private tag), just skip
 					return true
 				}
 
@@ -405,6 +450,11 @@ func extractDicom(version string) {
 		return true
 	})
 
+	uids := map[string]*UIDDef{}
+	if uidRegistryTbl := part6.Dict["table_A-1"]; uidRegistryTbl != nil {
+		uids = parseUIDRegistry(uidRegistryTbl)
+	}
+
     os.MkdirAll(fmt.Sprintf("../dicom%sdata", version), 0700)
 
 	out, err := os.Create(fmt.Sprintf("../dicom%sdata/dicom-%s.go", version, version))
@@ -417,13 +467,20 @@ func extractDicom(version string) {
 	fmt.Fprintf(out, "// Date Assembled: %s\n", time.Now().Format(time.UnixDate))
 	fmt.Fprintf(out, "package dicom%sdata\n", version)
 	fmt.Fprintf(out, `
+import "github.com/macadamian/dicom"
+
+func init() {
+	dicom.RegisterSchema(%q, func() string { return SchemaStr })
+}
+`, version)
+	fmt.Fprintf(out, `
 // Unmarshal this string into a github.com/macadamian/dicom SchemaDef using encoding/json package.
 // You can assign an empty string here afterwards to free memory.
 var SchemaStr =`)
 
 	fmt.Fprintf(out, "`\n")
 
-	od := SchemaDef{ClassDefs: sopClasses, TagDefs: tagdefs, ModuleDefs: modules}
+	od := SchemaDef{ClassDefs: sopClasses, TagDefs: tagdefs, ModuleDefs: modules, UIDs: uids, FileMeta: fileMeta, Commands: commands}
 	b, err := json.MarshalIndent(od, "", "\t")
 	if err != nil {
 		panic(err)
@@ -457,70 +514,426 @@ func findNodeByType(node *Node, space, local string) *Node {
 	return match
 }
 
-func parseTagPattern(pattern string) []dicomtag.Tag {
+// Parse a tag from the spec into the key of the schema (e.g. "(0008,001a)"). Repeating groups and
+// other ranges of tags are kept as patterns with "x" digits (e.g. "(60xx,3000)").
+func parseTagPattern(pattern string) (string, bool) {
 	pattern = strings.TrimSpace(pattern)
 
-	if !strings.Contains(pattern, ",") {
+	pieces := strings.Split(strings.Trim(pattern, "()"), ",")
+
+	if len(pieces) != 2 {
 		fmt.Printf("BAD TAG: %s\n", pattern)
-		return []dicomtag.Tag{}
+		return "", false
 	}
 
-	pieces := strings.Split(pattern, ",")
+	g := strings.ToLower(strings.TrimSpace(pieces[0]))
+	el := strings.ToLower(strings.TrimSpace(pieces[1]))
 
-	if len(pieces) != 2 {
-		fmt.Printf("BAD TAG: %s\n", pattern)
-		return []dicomtag.Tag{}
+	for _, digits := range []string{g, el} {
+		if len(digits) != 4 || strings.Trim(digits, "0123456789abcdefx") != "" {
+			fmt.Printf("BAD TAG: %s\n", pattern)
+			return "", false
+		}
 	}
 
-	g := pieces[0]
-	el := pieces[1]
+	return fmt.Sprintf("(%s,%s)", g, el), true
+}
+
+var (
+	markupRegexp      = regexp.MustCompile(`<[^>]*>`)
+	valueNumberRegexp = regexp.MustCompile(`Value (\d+)`)
+)
 
-	if strings.HasPrefix(g, "(") {
-		g = g[1:]
+// The plain text of a node and all of its descendants with the whitespace collapsed
+func nodeText(n Node) string {
+	s := html.UnescapeString(markupRegexp.ReplaceAllString(n.Content, " "))
+	return sanitize(strings.Join(strings.Fields(s), " "))
+}
+
+func attrValue(n Node, local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
 	}
+	return ""
+}
 
-	if strings.HasSuffix(el, ")") {
-		el = el[:len(el)-1]
+// Extract the Enumerated Values and Defined Terms from the description of an attribute. The lists
+// are usually in the description itself, otherwise they are looked up in the sections that the
+// description references (e.g. "See Section C.7.3.1.1.1 for Defined Terms").
+func extractTerms(desc Node, part *NodeDict) ([]Term, []Term) {
+	enums, defined := termLists(desc)
+	if len(enums) != 0 || len(defined) != 0 {
+		return enums, defined
 	}
 
-	g = strings.TrimSpace(g)
-	el = strings.TrimSpace(el)
+	walkNode([]Node{desc}, func(n Node) bool {
+		if n.XMLName.Local != "xref" {
+			return true
+		}
 
-	if g == "50xx" {
-		// Curve patterns have been retired for a long time (2004)
-		return []dicomtag.Tag{}
-	}
+		linkend := attrValue(n, "linkend")
+		if !strings.HasPrefix(linkend, "sect_") {
+			return true
+		}
+
+		if sect := part.Dict[linkend]; sect != nil {
+			e, d := termLists(*sect)
+			enums = append(enums, e...)
+			defined = append(defined, d...)
+		}
+		return true
+	})
+
+	return enums, defined
+}
 
-	gs := []string{g}
+// Find the variable lists in a node that are titled (or preceded by a paragraph) as
+// Enumerated Values or Defined Terms
+func termLists(node Node) ([]Term, []Term) {
+	enums, defined := []Term{}, []Term{}
+	label := ""
 
-	// Special repeating pattern for overlay group
-	if g == "60xx" {
-		gs = []string{}
+	walkNode([]Node{node}, func(n Node) bool {
+		if n.XMLName.Space != "http://docbook.org/ns/docbook" {
+			return true
+		}
+
+		switch n.XMLName.Local {
+		case "para":
+			label = nodeText(n)
+			return false
+		case "variablelist":
+			for _, c := range n.Nodes {
+				if c.XMLName.Local == "title" {
+					label = label + " " + nodeText(c)
+				}
+			}
 
-		for i := 0; i < 16; i++ {
-			for j := 0; j < 16; j++ {
-				gs = append(gs, fmt.Sprintf("60%X%X", i, j))
+			vn := 0
+			if m := valueNumberRegexp.FindStringSubmatch(label); m != nil {
+				vn, _ = strconv.Atoi(m[1])
 			}
+
+			terms := []Term{}
+			for _, entry := range n.Nodes {
+				if entry.XMLName.Local != "varlistentry" {
+					continue
+				}
+
+				t := Term{ValueNumber: vn}
+				for _, c := range entry.Nodes {
+					switch c.XMLName.Local {
+					case "term":
+						t.Value = nodeText(c)
+					case "listitem":
+						if p := findNodeByType(&c, "http://docbook.org/ns/docbook", "para"); p != nil {
+							t.Meaning = nodeText(*p)
+						}
+					}
+				}
+
+				if t.Value != "" {
+					terms = append(terms, t)
+				}
+			}
+
+			if strings.Contains(label, "Enumerated Value") {
+				enums = append(enums, terms...)
+			} else if strings.Contains(label, "Defined Term") {
+				defined = append(defined, terms...)
+			}
+
+			label = ""
+			return false
+		}
+
+		return true
+	})
+
+	return enums, defined
+}
+
+// The columns of a table by the text of their headers, or nil if the row isn't a header row
+func headerColumns(n Node) map[string]int {
+	if len(n.Nodes) == 0 || n.Nodes[0].XMLName.Local != "th" {
+		return nil
+	}
+
+	cols := map[string]int{}
+	for i, th := range n.Nodes {
+		cols[nodeText(th)] = i
+	}
+	return cols
+}
+
+// The text of a column of a row, or empty if the table doesn't have the column
+func columnText(n Node, cols map[string]int, headers ...string) string {
+	for _, h := range headers {
+		if i, ok := cols[h]; ok && i < len(n.Nodes) {
+			return nodeText(n.Nodes[i])
 		}
 	}
+	return ""
+}
 
-	tags := []dicomtag.Tag{}
+// Parse a registry of elements other than the data dictionary, such as the file meta elements of PS3.6
+// or the command elements of PS3.7, which have columns for the tag, name, VR, VM and (in the newer
+// versions) keyword of each element
+func parseElementRegistry(tbl *Node) map[string]*TagDef {
+	tagdefs := map[string]*TagDef{}
 
-	for _, gr := range gs {
-		group, err := strconv.ParseUint(gr, 16, 16)
-		if err != nil {
-			fmt.Printf("BAD TAG: %s\n", pattern)
-			return []dicomtag.Tag{}
+	var cols map[string]int
+	walkNode([]Node{*tbl}, func(n Node) bool {
+		if n.XMLName.Local != "tr" {
+			return true
 		}
-		element, err := strconv.ParseUint(el, 16, 16)
-		if err != nil {
-			fmt.Printf("BAD TAG: %s\n", pattern)
-			return []dicomtag.Tag{}
+
+		if hc := headerColumns(n); hc != nil {
+			cols = hc
+			return false
+		}
+		if cols == nil {
+			return false
+		}
+
+		t, ok := parseTagPattern(columnText(n, cols, "Tag"))
+		if !ok {
+			return false
 		}
 
-		t := dicomtag.Tag{uint16(group), uint16(element)}
-		tags = append(tags, t)
+		keyword := columnText(n, cols, "Keyword")
+		if keyword == "" {
+			// The keyword is the name without spaces and punctuation (e.g. "CommandGroupLength")
+			keyword = strings.Map(func(r rune) rune {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					return r
+				}
+				return -1
+			}, strings.Title(columnText(n, cols, "Name", "Message Field")))
+		}
+
+		// Skip retired tags
+		if strings.Contains(columnText(n, cols, ""), "RET") {
+			return false
+		}
+
+		tagdefs[t] = &TagDef{
+			Keyword: keyword,
+			VR:      strings.Split(columnText(n, cols, "VR"), " or "),
+			VM:      columnText(n, cols, "VM"),
+		}
+
+		return false
+	})
+
+	return tagdefs
+}
+
+// Parse the elements of the File Meta Information of PS3.10 along with their types
+func parseFileMeta(tbl *Node) []TagUsage {
+	usages := []TagUsage{}
+
+	var cols map[string]int
+	walkNode([]Node{*tbl}, func(n Node) bool {
+		if n.XMLName.Local != "tr" {
+			return true
+		}
+
+		if hc := headerColumns(n); hc != nil {
+			cols = hc
+			return false
+		}
+		if cols == nil {
+			return false
+		}
+
+		if t, ok := parseTagPattern(columnText(n, cols, "Tag")); ok {
+			usages = append(usages, TagUsage{Path: []string{t}, Type: columnText(n, cols, "Type")})
+		}
+
+		return false
+	})
+
+	return usages
+}
+
+var (
+	messageFieldsRegexp = regexp.MustCompile(`^([A-Z]-[A-Z-]+)-(RQ|RSP) Message Fields$`)
+	parametersRegexp    = regexp.MustCompile(`^([A-Z]-[A-Z-]+) Parameters$`)
+)
+
+// Parse the command elements of the DIMSE messages of PS3.7 along with their types. The message
+// field tables (e.g. "C-STORE-RQ Message Fields") list the elements of each message and the
+// parameter tables of the services (e.g. "C-STORE Parameters") have their usages in the requests and
+// responses by the name of the field.
+func parseCommands(part *NodeDict) map[string][]TagUsage {
+	// Map of service to the usages of its parameters by name, for the request and the response
+	usages := map[string][2]map[string]string{}
+	fields := map[string]*Node{}
+	for _, tbl := range part.Dict {
+		if tbl.XMLName.Local != "table" {
+			continue
+		}
+		caption := findNodeByType(tbl, "http://docbook.org/ns/docbook", "caption")
+		if caption == nil {
+			continue
+		}
+
+		name := nodeText(*caption)
+		if messageFieldsRegexp.MatchString(name) {
+			fields[name] = tbl
+		} else if m := parametersRegexp.FindStringSubmatch(name); m != nil {
+			usages[m[1]] = parseParameterUsages(tbl)
+		}
 	}
 
-	return tags
+	commands := map[string][]TagUsage{}
+	for name, tbl := range fields {
+		m := messageFieldsRegexp.FindStringSubmatch(name)
+		direction := 0
+		if m[2] == "RSP" {
+			direction = 1
+		}
+		params := usages[m[1]][direction]
+
+		var cols map[string]int
+		walkNode([]Node{*tbl}, func(n Node) bool {
+			if n.XMLName.Local != "tr" {
+				return true
+			}
+
+			if hc := headerColumns(n); hc != nil {
+				cols = hc
+				return false
+			}
+			if cols == nil {
+				return false
+			}
+
+			t, ok := parseTagPattern(columnText(n, cols, "Tag"))
+			if !ok {
+				return false
+			}
+
+			// The fields that aren't parameters of the service (e.g. Command Group Length and Command
+			// Field) are always present
+			typ := "1"
+			if usage, ok := params[columnText(n, cols, "Message Field")]; ok {
+				if typ, ok = commandType(usage); !ok {
+					return false
+				}
+			}
+			commands[m[1]+"-"+m[2]] = append(commands[m[1]+"-"+m[2]], TagUsage{Path: []string{t}, Type: typ})
+
+			return false
+		})
+	}
+
+	return commands
+}
+
+// Parse the usages of the parameters of a DIMSE service in the request (Req/Ind) and the response
+// (Rsp/Conf) by the name of the parameter
+func parseParameterUsages(tbl *Node) [2]map[string]string {
+	usages := [2]map[string]string{{}, {}}
+
+	var cols map[string]int
+	walkNode([]Node{*tbl}, func(n Node) bool {
+		if n.XMLName.Local != "tr" {
+			return true
+		}
+
+		if hc := headerColumns(n); hc != nil {
+			cols = hc
+			return false
+		}
+		if cols == nil {
+			return false
+		}
+
+		name := columnText(n, cols, "Parameter Name")
+		if name == "" {
+			return false
+		}
+		usages[0][name] = columnText(n, cols, "Req/Ind", "Req/ind", "Req/Ind.")
+		usages[1][name] = columnText(n, cols, "Rsp/Conf", "Rsp/conf", "Rsp/Conf.")
+
+		return false
+	})
+
+	return usages
+}
+
+// The type of a command element for the usage of its parameter in PS3.7 Section 3.10 (e.g. "U(=)"),
+// or false if the parameter isn't used in the message
+func commandType(usage string) (string, bool) {
+	usage = strings.TrimSpace(usage)
+	if usage == "" {
+		return "", false
+	}
+
+	switch usage[0] {
+	case 'M', '=':
+		return "1", true
+	case 'U':
+		return "3", true
+	case 'C':
+		return "1C", true
+	}
+	return "", false
+}
+
+// Parse the UID registry of PS3.6, which has columns for the value, name, type and (in the newer
+// versions) keyword of each UID
+func parseUIDRegistry(tbl *Node) map[string]*UIDDef {
+	uids := map[string]*UIDDef{}
+
+	valueCol, nameCol, keywordCol, typeCol := -1, -1, -1, -1
+	walkNode([]Node{*tbl}, func(n Node) bool {
+		if n.XMLName.Local != "tr" {
+			return true
+		}
+
+		if len(n.Nodes) > 0 && n.Nodes[0].XMLName.Local == "th" {
+			for i, th := range n.Nodes {
+				switch nodeText(th) {
+				case "UID Value":
+					valueCol = i
+				case "UID Name":
+					nameCol = i
+				case "UID Keyword":
+					keywordCol = i
+				case "UID Type":
+					typeCol = i
+				}
+			}
+			return false
+		}
+
+		if valueCol < 0 || nameCol < 0 || typeCol < 0 || len(n.Nodes) <= valueCol || len(n.Nodes) <= nameCol || len(n.Nodes) <= typeCol {
+			return false
+		}
+
+		value := nodeText(n.Nodes[valueCol])
+		if value == "" {
+			return false
+		}
+
+		name := nodeText(n.Nodes[nameCol])
+		ud := &UIDDef{
+			Name:    strings.TrimSpace(strings.Replace(name, "(Retired)", "", -1)),
+			Type:    nodeText(n.Nodes[typeCol]),
+			Retired: strings.Contains(name, "(Retired)"),
+		}
+		if keywordCol >= 0 && len(n.Nodes) > keywordCol {
+			ud.Keyword = nodeText(n.Nodes[keywordCol])
+		}
+		uids[value] = ud
+
+		return false
+	})
+
+	return uids
 }
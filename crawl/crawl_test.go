@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func testNode(t *testing.T, s string) Node {
+	n := Node{}
+	if err := xml.Unmarshal([]byte(s), &n); err != nil {
+		t.Fatalf("Unable to parse %q: %v", s, err)
+	}
+	return n
+}
+
+func TestExtractTerms(t *testing.T) {
+	desc := testNode(t, `<td xmlns="http://docbook.org/ns/docbook">
+		<para>Sex of the named Patient.</para>
+		<variablelist><title>Enumerated Values:</title>
+			<varlistentry><term>M</term><listitem><para>male</para></listitem></varlistentry>
+			<varlistentry><term>F</term><listitem><para>female</para></listitem></varlistentry>
+		</variablelist>
+		<para>Value 1 shall have the Defined Terms:</para>
+		<variablelist>
+			<varlistentry><term>ORIGINAL</term><listitem><para>original image</para></listitem></varlistentry>
+		</variablelist>
+	</td>`)
+
+	enums, defined := extractTerms(desc, &NodeDict{Dict: map[string]*Node{}})
+	wantEnums := []Term{{Value: "M", Meaning: "male"}, {Value: "F", Meaning: "female"}}
+	wantDefined := []Term{{Value: "ORIGINAL", Meaning: "original image", ValueNumber: 1}}
+	if !reflect.DeepEqual(enums, wantEnums) || !reflect.DeepEqual(defined, wantDefined) {
+		t.Errorf("extractTerms = %+v, %+v, want %+v, %+v", enums, defined, wantEnums, wantDefined)
+	}
+}
+
+func TestExtractTermsReference(t *testing.T) {
+	// The terms are in the section that the description references
+	sect := testNode(t, `<section xmlns="http://docbook.org/ns/docbook" xml:id="sect_C.7.3.1.1.1">
+		<para>Defined Terms for the Modality (0008,0060) are:</para>
+		<variablelist>
+			<varlistentry><term>CT</term><listitem><para>Computed Tomography</para></listitem></varlistentry>
+			<varlistentry><term>MR</term><listitem><para>Magnetic Resonance</para></listitem></varlistentry>
+		</variablelist>
+	</section>`)
+	desc := testNode(t, `<td xmlns="http://docbook.org/ns/docbook">
+		<para>Type of device. See <xref linkend="sect_C.7.3.1.1.1"/> for Defined Terms.</para>
+	</td>`)
+
+	enums, defined := extractTerms(desc, &NodeDict{Dict: map[string]*Node{"sect_C.7.3.1.1.1": &sect}})
+	wantDefined := []Term{{Value: "CT", Meaning: "Computed Tomography"}, {Value: "MR", Meaning: "Magnetic Resonance"}}
+	if len(enums) != 0 || !reflect.DeepEqual(defined, wantDefined) {
+		t.Errorf("extractTerms = %+v, %+v, want no Enumerated Values and %+v", enums, defined, wantDefined)
+	}
+}
+
+func TestCommandType(t *testing.T) {
+	tests := []struct {
+		usage string
+		typ   string
+		ok    bool
+	}{
+		{"M", "1", true},
+		{" U(=)", "3", true},
+		{"=", "1", true},
+		{"C", "1C", true},
+		{"-", "", false},
+		{"", "", false},
+		{"  ", "", false},
+	}
+
+	for _, tt := range tests {
+		if typ, ok := commandType(tt.usage); typ != tt.typ || ok != tt.ok {
+			t.Errorf("commandType(%q) = %q, %v, want %q, %v", tt.usage, typ, ok, tt.typ, tt.ok)
+		}
+	}
+}
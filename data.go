@@ -5,9 +5,21 @@ type SchemaDef struct {
 	// List of SOP Class definitions that form this version of the schema.
 	ClassDefs  []ClassDef
 	// Map of DICOM tag (e.g. "(0008,001a)") to DICOM tag definitions included in this version of the schema.
+	// Repeating groups and other ranges of tags are keyed by their pattern (e.g. "(60xx,3000)"), see
+	// TagPattern and LookupTag.
 	TagDefs    map[string]TagDef
 	// Map of module name to their definition in this version of the schema.
 	ModuleDefs map[string]ModuleDef
+	// Map of UID (e.g. "1.2.840.10008.1.2.1") to the entries of the UID registry in PS3.6 Annex A in this version
+	// of the schema. See SchemaDef.LookupUID.
+	UIDs map[string]UIDDef `json:",omitempty"`
+	// The elements of the File Meta Information (group 0002) of PS3.10 with their types in this version of the schema.
+	// See ValidateFileMeta.
+	FileMeta []TagUsage `json:",omitempty"`
+	// Map of DIMSE message (e.g. "C-STORE-RQ") to the elements of its Command Set (group 0000) of PS3.7 with their
+	// types in this version of the schema. The usages of PS3.7 are given as types: "M" and "=" as "1", "U" as "3"
+	// and "C" as "1C".
+	Commands map[string][]TagUsage `json:",omitempty"`
 }
 
 // An SOP Class definition describes the name, SOPClassUID and modules that form a valid DICOM
@@ -36,7 +48,8 @@ type ModuleDef struct {
 // A tag usage is a usage of one or more tags in a path within the DICOM instance with a
 // type that determines whether the tag is required, optional, may be present or not.
 type TagUsage struct {
-	// A path within a DICOM instance of tags for this usage (e.g. ["(0040,0555)","(0040,a040)"]) 
+	// A path within a DICOM instance of tags for this usage (e.g. ["(0040,0555)","(0040,a040)"]). Tags of
+	// repeating groups are kept as their pattern (e.g. ["(60xx,3000)"]).
 	Path []string
 	// A type of this usage:
 	//   "1" Required to be in the SOP Instance and shall have a valid value.
@@ -45,6 +58,23 @@ type TagUsage struct {
 	//   "1C" Conditional. If a condition is met, then it is a Type 1 (required, cannot be zero). If condition is not met, then the tag is not sent.
 	//   "2C" Conditional. If condition is met, then it is a Type 2 (required, zero length OK). If condition is not met, then the tag is not sent.
 	Type string
+	// EnumeratedValues are the only values allowed for the tag in this usage, if the specification lists them
+	// (e.g. "M", "F" and "O" for Patient's Sex).
+	EnumeratedValues []Term `json:",omitempty"`
+	// DefinedTerms are the values listed by the specification for the tag in this usage that may be extended
+	// with other values (e.g. "CT" and "MR" for Modality).
+	DefinedTerms []Term `json:",omitempty"`
+}
+
+// A term is one of the Enumerated Values or Defined Terms of a tag usage.
+type Term struct {
+	// The value as it appears in a DICOM instance (e.g. "M")
+	Value string
+	// The meaning of the value (e.g. "male"), if the specification provides one
+	Meaning string `json:",omitempty"`
+	// ValueNumber is the position (starting at 1) of the value in a multi-valued tag that this term applies
+	// to (e.g. "Value 1" of Image Type), or 0 if it applies to any of the values.
+	ValueNumber int `json:",omitempty"`
 }
 
 // A tag defintion provide information about a DICOM tag within this version of the specification
@@ -57,10 +87,23 @@ type TagDef struct {
 	// different representations.
 	// See https://godoc.org/github.com/gradienthealth/dicom#Element for how these are mapped to Go types.
 	VR         []string
-	// The VM (Value Multiplicity) defines the range of the number of values for this tag.
-	VM         string
+	// The VM (Value Multiplicity) defines the range of the number of values for this tag. It is
+	// represented in the JSON form of the schema as a string (e.g. "1-n").
+	VM         VM
 	// Deidentify provides metadata regarding the handling of this tag when deidentifying DICOM information.
 	// If empty, the spec does not expect that this tag is likely to contain personal health information. Otherwise,
 	// values are described in Section E.1.1 of PS 3.15 of the DICOM spec for detailed explanations.
 	Deidentify string
-}
\ No newline at end of file
+}
+
+// A UID definition is an entry of the UID registry (Table A-1 of PS3.6) with the well-known UIDs of the specification.
+type UIDDef struct {
+	// The name of the UID without the "(Retired)" marker (e.g. "Explicit VR Little Endian")
+	Name string
+	// The keyword of the UID (e.g. "ExplicitVRLittleEndian"), if the specification provides one
+	Keyword string `json:",omitempty"`
+	// The type of the UID (e.g. "Transfer Syntax"), see UIDType for the common types
+	Type UIDType
+	// Retired is true for UIDs that have been retired from the specification.
+	Retired bool `json:",omitempty"`
+}
@@ -0,0 +1,87 @@
+package dicom
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/macadamian/dicom/dicomvalue"
+)
+
+// DateShifter provides the number of days to shift the dates of a patient by for the Retain
+// Longitudinal Temporal Information with Modified Dates option. The shift is a random looking
+// number of days before the original dates that is derived from a keyed hash (HMAC-SHA256) of the
+// Patient ID, so every study of a patient is shifted by the same offset, including the studies of
+// other batches that use the same key, and the intervals between them are kept.
+//
+//	shifter := dicom.NewDateShifter(key, 365)
+//	err := dicom.Deidentify(ds, schema, dicom.DeidentifyOptions{
+//		Options:   []dicom.DeidentifyOption{dicom.RetainLongitudinalModifiedDatesOption},
+//		DateShift: shifter.ShiftDays,
+//	})
+type DateShifter struct {
+	key     []byte
+	maxDays int
+}
+
+// NewDateShifter provides a DateShifter with a secret key, which should be at least 32 random
+// bytes, that shifts the dates by 1 to maxDays days into the past.
+func NewDateShifter(key []byte, maxDays int) *DateShifter {
+	if maxDays < 1 {
+		maxDays = 1
+	}
+	return &DateShifter{key: append([]byte{}, key...), maxDays: maxDays}
+}
+
+// ShiftDays provides the (negative) number of days to shift the dates of a patient by.
+func (s *DateShifter) ShiftDays(patientID string) (int, error) {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.TrimRight(patientID, "\x00 ")))
+	n := binary.BigEndian.Uint64(mac.Sum(nil)[:8])
+	return -1 - int(n%uint64(s.maxDays)), nil
+}
+
+// Shift the dates of a DA or DT value by a number of days, keeping the precision and offset of
+// date times. Times (TM) are kept since they are only shifted by whole days. Each of the values of
+// a multi-valued element (e.g. "20190315\20190401") is shifted.
+func shiftDate(vr string, value string, days int) (string, error) {
+	value = strings.TrimRight(value, "\x00 ")
+	if value == "" {
+		return value, nil
+	}
+
+	if strings.Contains(value, "\\") {
+		values := strings.Split(value, "\\")
+		for i, v := range values {
+			shifted, err := shiftDate(vr, v, days)
+			if err != nil {
+				return "", err
+			}
+			values[i] = shifted
+		}
+		return strings.Join(values, "\\"), nil
+	}
+
+	switch vr {
+	case "DA":
+		d, err := dicomvalue.ParseDate(value)
+		if err != nil {
+			return "", err
+		}
+		t := d.Time().AddDate(0, 0, days)
+		return dicomvalue.Date{Year: t.Year(), Month: t.Month(), Day: t.Day()}.String(), nil
+	case "DT":
+		dt, err := dicomvalue.ParseDateTime(value)
+		if err != nil {
+			return "", err
+		}
+		dt.Time = dt.Time.AddDate(0, 0, days)
+		return dt.String(), nil
+	case "TM":
+		return value, nil
+	}
+
+	return "", fmt.Errorf("Unable to shift a value with VR %s", vr)
+}
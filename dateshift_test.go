@@ -0,0 +1,89 @@
+package dicom
+
+import "testing"
+
+func TestShiftDate(t *testing.T) {
+	tests := []struct {
+		vr    string
+		value string
+		days  int
+		want  string
+	}{
+		{"DA", "20190315", -10, "20190305"},
+		{"DA", "20190301", -1, "20190228"},
+		{"DA", "20200301", -1, "20200229"},
+		{"DA", "20190105", -365, "20180105"},
+		{"DA", "20190315 ", -10, "20190305"},
+		{"DA", "", -10, ""},
+		// Each of the values of a multi-valued element is shifted
+		{"DA", "20190315\\20190401", -10, "20190305\\20190322"},
+		{"DA", "20190315\\\\20190401 ", -10, "20190305\\\\20190322"},
+		// The precision and the offset of the date times are kept
+		{"DT", "20190315153000.25-0500", -10, "20190305153000.25-0500"},
+		{"DT", "201903151530+0100", -15, "201902281530+0100"},
+		{"DT", "20190315", -10, "20190305"},
+		{"DT", "2019", -10, "2018"},
+		{"DT", "20190315153000\\2019", -10, "20190305153000\\2018"},
+		// Times are only shifted by whole days
+		{"TM", "153000", -10, "153000"},
+	}
+
+	for _, tt := range tests {
+		got, err := shiftDate(tt.vr, tt.value, tt.days)
+		if err != nil {
+			t.Errorf("shiftDate(%q, %q, %d) error: %v", tt.vr, tt.value, tt.days, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("shiftDate(%q, %q, %d) = %q, want %q", tt.vr, tt.value, tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestShiftDateInvalid(t *testing.T) {
+	tests := []struct {
+		vr    string
+		value string
+	}{
+		{"DA", "2019-03-15"},
+		{"DA", "20190230"},
+		{"DA", "20190315\\2019-03-15"},
+		{"DT", "201"},
+		{"LO", "20190315"},
+	}
+
+	for _, tt := range tests {
+		if got, err := shiftDate(tt.vr, tt.value, -10); err == nil {
+			t.Errorf("shiftDate(%q, %q, -10) = %q, want an error", tt.vr, tt.value, got)
+		}
+	}
+}
+
+func TestDateShifterShiftDays(t *testing.T) {
+	s := NewDateShifter([]byte("0123456789abcdef0123456789abcdef"), 30)
+
+	seen := map[int]bool{}
+	for _, id := range []string{"12345", "12346", "A-1", "B-2", "C-3", "D-4", "E-5", "F-6"} {
+		days, err := s.ShiftDays(id)
+		if err != nil {
+			t.Fatalf("ShiftDays(%q) error: %v", id, err)
+		}
+		if days < -30 || days > -1 {
+			t.Errorf("ShiftDays(%q) = %d, want between -30 and -1", id, days)
+		}
+		seen[days] = true
+
+		// The same patient is always shifted by the same days, also by another shifter with the same key
+		again, _ := NewDateShifter([]byte("0123456789abcdef0123456789abcdef"), 30).ShiftDays(id + " ")
+		if again != days {
+			t.Errorf("ShiftDays(%q) = %d, then %d", id, days, again)
+		}
+	}
+	if len(seen) < 2 {
+		t.Errorf("ShiftDays gave the same shift to every patient")
+	}
+
+	if days, _ := NewDateShifter([]byte("key"), 0).ShiftDays("12345"); days != -1 {
+		t.Errorf("ShiftDays with a maxDays of 0 = %d, want -1", days)
+	}
+}
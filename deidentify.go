@@ -0,0 +1,492 @@
+package dicom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// A DeidentifyAction is an action code of the confidentiality profiles in Table E.1-1 of PS3.15 for
+// an attribute (e.g. "X" to remove it). Some attributes have a combination of actions that depends
+// on the IOD, such as "X/Z" for attributes that are removed unless they are Type 2.
+type DeidentifyAction string
+
+const (
+	// DeidentifyDummy replaces the value with a non-zero length dummy value that is consistent with the VR.
+	DeidentifyDummy DeidentifyAction = "D"
+	// DeidentifyZero replaces the value with a zero length value, or a dummy value for Type 1 attributes.
+	DeidentifyZero DeidentifyAction = "Z"
+	// DeidentifyRemove removes the attribute.
+	DeidentifyRemove DeidentifyAction = "X"
+	// DeidentifyKeep keeps the value unchanged, or cleans it for sequences.
+	DeidentifyKeep DeidentifyAction = "K"
+	// DeidentifyClean replaces the identifying information in the value with values of similar meaning.
+	DeidentifyClean DeidentifyAction = "C"
+	// DeidentifyUID replaces the UID with a different UID that is consistent within the set of instances.
+	DeidentifyUID DeidentifyAction = "U"
+
+	DeidentifyZeroOrDummy         DeidentifyAction = "Z/D"
+	DeidentifyRemoveOrZero        DeidentifyAction = "X/Z"
+	DeidentifyRemoveOrDummy       DeidentifyAction = "X/D"
+	DeidentifyRemoveOrZeroOrDummy DeidentifyAction = "X/Z/D"
+	DeidentifyRemoveOrZeroOrUID   DeidentifyAction = "X/Z/U*"
+)
+
+// A DeidentifyOption is an option of the confidentiality profiles in PS3.15 (e.g. "Retain Safe Private")
+// that Deidentify applies along with the Basic Profile.
+type DeidentifyOption string
+
+const (
+	RetainSafePrivateOption               DeidentifyOption = "Retain Safe Private"
+	RetainLongitudinalModifiedDatesOption DeidentifyOption = "Retain Longitudinal Temporal Information with Modified Dates"
+)
+
+// DeidentifyOptions configures Deidentify.
+type DeidentifyOptions struct {
+	// The options of the confidentiality profiles to apply along with the Basic Profile.
+	Options []DeidentifyOption
+	// ReplaceUID provides the replacement of a UID for the "U" action, which must be the same for
+	// each occurrence of the UID so that the references between instances are kept. Use the RemapUID
+	// of a UIDRemapper to keep the references between the datasets of a batch. A new random UID (with
+	// the "2.25." root) is used for each distinct UID in the dataset if it is nil.
+	ReplaceUID func(uid string) (string, error)
+	// DateShift provides the number of days to shift the dates of a patient (by Patient ID) by for
+	// the Retain Longitudinal Temporal Information with Modified Dates option, see DateShifter. It
+	// is required when that option is used.
+	DateShift func(patientID string) (int, error)
+	// Pseudonymize provides the pseudonym of a value of an attribute for reversible
+	// de-identification, see PseudonymStore. The attributes get an empty or dummy value if it is nil.
+	Pseudonymize func(t dicomtag.Tag, value string) (string, error)
+}
+
+// The action for the dates and times that are shifted by the Retain Longitudinal Temporal
+// Information with Modified Dates option
+const deidentifyShift DeidentifyAction = "shift"
+
+// The File Meta Information elements that are kept when they aren't in the schema, which describe
+// the encoding of the file rather than where it came from
+var fileMetaKept = map[dicomtag.Tag]bool{
+	dicomtag.FileMetaInformationVersion: true,
+	dicomtag.MediaStorageSOPClassUID:    true,
+	dicomtag.TransferSyntaxUID:          true,
+	dicomtag.ImplementationClassUID:     true,
+	dicomtag.ImplementationVersionName:  true,
+}
+
+// A code is the triple of values that identifies a coded concept in a code sequence item.
+type Code struct {
+	CodeValue              string
+	CodingSchemeDesignator string
+	CodeMeaning            string
+}
+
+// The codes of the de-identification methods for the Deidentification Method Code Sequence (CID 7050)
+var deidentifyMethodCodes = map[DeidentifyOption]Code{
+	RetainSafePrivateOption:               {"113111", "DCM", "Retain Safe Private Option"},
+	RetainLongitudinalModifiedDatesOption: {"113107", "DCM", "Retain Longitudinal Temporal Information Modified Dates Option"},
+}
+
+var basicProfileCode = Code{"113100", "DCM", "Basic Application Confidentiality Profile"}
+
+// Deidentify applies the actions of the Basic Application Level Confidentiality Profile of PS3.15,
+// and the provided options, to a dataset in place. The action of each element, including those in
+// the items of sequences, comes from the Deidentify of its tag definition in the schema:
+//
+//	"X" removes the element.
+//	"Z" replaces the value with an empty value, or a dummy value for Type 1 attributes.
+//	"D" replaces the value with a dummy value for the VR (e.g. "ANONYMIZED" or 19000101).
+//	"C" is treated like "D" since the values can't be cleaned without understanding them.
+//	"U" replaces the UIDs with the ReplaceUID of the options.
+//	"K" keeps the value.
+//
+// The compound actions are resolved with the Type of the attribute in the IOD of the SOP Class of
+// the dataset: Type 1 attributes get the dummy ("D") or UID ("U") action, Type 2 attributes get the
+// empty ("Z") action and the others are removed ("X"). Attributes that aren't in the IOD are
+// removed. The items of sequences that are kept are de-identified as well. The Media Storage SOP
+// Instance UID (0002,0003) is always replaced to follow the SOP Instance UID, and the UIDs of the
+// UID registry of the schema (see SchemaDef.LookupUID) are never replaced.
+//
+// Attributes that aren't in the schema are removed, since they may identify the patient as well as
+// any other attribute. The File Meta Information elements that describe the encoding of the file
+// (e.g. the Transfer Syntax UID) are kept when the schema doesn't have them.
+//
+// Private attributes are removed. With the Retain Safe Private option, those that have the "K" action
+// (TagDef.Deidentify) in their registered private dictionary (see RegisterPrivateDictionary) are kept. Group lengths other than the
+// File Meta Information Group Length are removed since they would no longer be correct.
+//
+// With the Retain Longitudinal Temporal Information with Modified Dates option, the dates (DA and
+// DT) of the attributes that have an action of the Basic Profile are shifted instead by the days of
+// the DateShift of the options for the Patient ID of the dataset, so the intervals between the
+// studies of a patient are kept. The times (TM) are kept since the dates are shifted by whole days.
+// Values that aren't valid dates are replaced with a dummy value.
+//
+// Other options are an error since their actions aren't known.
+//
+// With the Pseudonymize of the options, the attributes with a person name (PN) or identifier (LO or
+// SH) value that would get an empty ("Z") or dummy ("D") value, such as the Patient ID, Patient's
+// Name and Accession Number, get a pseudonym instead so they can be re-identified later.
+//
+// Finally, Patient Identity Removed (0012,0062) is set to "YES" and De-identification Method
+// (0012,0063) along with its code sequence describe the profile and options that were applied.
+func Deidentify(ds *dicom.DataSet, schema *SchemaDef, opts DeidentifyOptions) error {
+	d := &deidentifier{
+		schema:   schema,
+		options:  opts.Options,
+		patterns: tagPatterns(schema),
+		types:    map[string]string{},
+		uids:     map[string]string{},
+		replace:  opts.ReplaceUID,
+
+		pseudonymize: opts.Pseudonymize,
+	}
+	if d.replace == nil {
+		d.replace = randomUID
+	}
+
+	for _, option := range opts.Options {
+		if _, ok := deidentifyMethodCodes[option]; !ok {
+			return fmt.Errorf("The %s option is not supported", option)
+		}
+	}
+
+	if d.hasOption(RetainLongitudinalModifiedDatesOption) {
+		if opts.DateShift == nil {
+			return fmt.Errorf("The %s option requires a DateShift", RetainLongitudinalModifiedDatesOption)
+		}
+		patientID := ""
+		if pe, err := ds.FindElementByTag(dicomtag.PatientID); err == nil {
+			patientID, _ = pe.GetString()
+		}
+		days, err := opts.DateShift(patientID)
+		if err != nil {
+			return fmt.Errorf("Unable to shift the dates: %v", err)
+		}
+		d.shift = true
+		d.shiftDays = days
+	}
+
+	if sce, err := ds.FindElementByTag(dicomtag.SOPClassUID); err == nil {
+		uid, _ := sce.GetString()
+		d.addTypes(strings.TrimRight(uid, "\x00 "))
+	}
+
+	elems, err := d.elements(ds.Elements, nil)
+	if err != nil {
+		return err
+	}
+
+	methods := []interface{}{basicProfileCode.CodeMeaning}
+	items := []interface{}{methodCodeItem(basicProfileCode)}
+	for _, option := range opts.Options {
+		code := deidentifyMethodCodes[option]
+		methods = append(methods, code.CodeMeaning)
+		items = append(items, methodCodeItem(code))
+	}
+
+	es := elementSet{}
+	es.add(&dicom.Element{Tag: dicomtag.PatientIdentityRemoved, VR: "CS", Value: []interface{}{"YES"}})
+	es.add(&dicom.Element{Tag: dicomtag.DeidentificationMethod, VR: "LO", Value: methods})
+	es.add(&dicom.Element{Tag: dicomtag.DeidentificationMethodCodeSequence, VR: "SQ", Value: items})
+	for _, e := range elems {
+		es.add(e)
+	}
+	ds.Elements = es.sorted()
+
+	return nil
+}
+
+type deidentifier struct {
+	schema   *SchemaDef
+	options  []DeidentifyOption
+	patterns []patternDef
+	// The strongest Type ("1", "2" or "3") of each path of tags (see pathKey) in the IOD
+	types   map[string]string
+	uids    map[string]string
+	replace func(uid string) (string, error)
+	// Whether the dates are shifted, and by how many days
+	shift        bool
+	shiftDays    int
+	pseudonymize func(t dicomtag.Tag, value string) (string, error)
+}
+
+// Record the Types of the attributes of the IOD of a SOP Class, keeping the strongest Type of the
+// attributes that are in more than one module
+func (d *deidentifier) addTypes(sopClassUID string) {
+	for _, cd := range d.schema.ClassDefs {
+		if cd.SOPClassUid != sopClassUID {
+			continue
+		}
+		for _, mu := range cd.Modules {
+			for _, tu := range d.schema.ModuleDefs[mu.Name].Tags {
+				key, err := pathKey(tu.Path)
+				if err != nil {
+					continue
+				}
+				typ := strings.TrimSpace(tu.Type)
+				if len(typ) > 1 {
+					// The conditional types are treated as if their condition is met
+					typ = typ[:1]
+				}
+				if prev, ok := d.types[key]; !ok || typ < prev {
+					d.types[key] = typ
+				}
+			}
+		}
+	}
+}
+
+// De-identify the elements of a dataset or sequence item, providing the elements that are kept
+func (d *deidentifier) elements(elems []*dicom.Element, path []string) ([]*dicom.Element, error) {
+	kept := []*dicom.Element{}
+
+	for _, e := range elems {
+		epath := append(append([]string{}, path...), e.Tag.String())
+
+		action := d.action(elems, e, epath)
+		if d.pseudonymize != nil && pseudonymVRs[e.VR] && (action == DeidentifyZero || action == DeidentifyDummy) {
+			if err := d.pseudonyms(e); err != nil {
+				return nil, err
+			}
+			action = DeidentifyKeep
+		}
+
+		switch action {
+		case DeidentifyRemove:
+			continue
+		case DeidentifyZero:
+			e.Value = []interface{}{}
+		case DeidentifyDummy, DeidentifyClean:
+			if e.VR == "UI" {
+				if err := d.replaceUIDs(e); err != nil {
+					return nil, err
+				}
+			} else if e.VR != "SQ" {
+				e.Value = []interface{}{dummyValue(e.VR)}
+			}
+		case DeidentifyUID:
+			if err := d.replaceUIDs(e); err != nil {
+				return nil, err
+			}
+		case deidentifyShift:
+			for i, v := range e.Value {
+				if s, ok := v.(string); ok {
+					shifted, err := shiftDate(e.VR, s, d.shiftDays)
+					if err != nil {
+						shifted = dummyValue(e.VR).(string)
+					}
+					e.Value[i] = shifted
+				}
+			}
+		}
+
+		if e.VR == "SQ" {
+			for _, item := range e.Value {
+				ie, ok := item.(*dicom.Element)
+				if !ok || ie.Tag != dicomtag.Item {
+					continue
+				}
+				ielems, err := d.elements(itemElements(ie), epath)
+				if err != nil {
+					return nil, err
+				}
+				ie.Value = elementValues(ielems)
+			}
+		}
+
+		kept = append(kept, e)
+	}
+
+	// Remove the Private Creators whose blocks no longer have any elements
+	blocks := map[dicomtag.Tag]bool{}
+	for _, e := range kept {
+		if e.Tag.Group%2 == 1 && e.Tag.Element >= 0x1000 {
+			blocks[dicomtag.Tag{Group: e.Tag.Group, Element: e.Tag.Element >> 8}] = true
+		}
+	}
+	elems = kept
+	kept = kept[:0]
+	for _, e := range elems {
+		if e.Tag.Group%2 == 1 && e.Tag.Element >= 0x0010 && e.Tag.Element <= 0x00ff && !blocks[e.Tag] {
+			continue
+		}
+		kept = append(kept, e)
+	}
+
+	return kept, nil
+}
+
+// The action for an element, with the compound actions resolved
+func (d *deidentifier) action(elems []*dicom.Element, e *dicom.Element, path []string) DeidentifyAction {
+	t := e.Tag
+
+	if t == dicomtag.MediaStorageSOPInstanceUID {
+		return DeidentifyUID
+	}
+	if t.Element == 0x0000 {
+		if t.Group == dicomtag.MetadataGroup {
+			return DeidentifyKeep
+		}
+		return DeidentifyRemove
+	}
+
+	if t.Group%2 == 1 {
+		if !d.hasOption(RetainSafePrivateOption) {
+			return DeidentifyRemove
+		}
+		if t.Element >= 0x0010 && t.Element <= 0x00ff && registeredPrivate(elems, t) {
+			return DeidentifyKeep
+		}
+		if _, td, ok := lookupPrivateTag(elems, t); ok && DeidentifyAction(td.Deidentify) == DeidentifyKeep {
+			return DeidentifyKeep
+		}
+		return DeidentifyRemove
+	}
+
+	td, ok := lookupTag(d.schema, d.patterns, t)
+	if !ok {
+		if t.Group == dicomtag.MetadataGroup && fileMetaKept[t] {
+			return DeidentifyKeep
+		}
+		return DeidentifyRemove
+	}
+	action := DeidentifyAction(td.Deidentify)
+	if d.shift && action != "" && (e.VR == "DA" || e.VR == "DT" || e.VR == "TM") {
+		return deidentifyShift
+	}
+
+	typ := ""
+	if key, err := pathKey(path); err == nil {
+		typ = d.types[key]
+	}
+
+	switch action {
+	case DeidentifyZero, DeidentifyZeroOrDummy:
+		if typ == "1" {
+			return DeidentifyDummy
+		}
+		return DeidentifyZero
+	case DeidentifyRemoveOrZero, DeidentifyRemoveOrDummy, DeidentifyRemoveOrZeroOrDummy, DeidentifyRemoveOrZeroOrUID:
+		switch {
+		case typ == "1" && action == DeidentifyRemoveOrZeroOrUID:
+			return DeidentifyUID
+		case typ == "1" && action != DeidentifyRemoveOrZero:
+			return DeidentifyDummy
+		case typ == "1" || typ == "2":
+			if action == DeidentifyRemoveOrDummy {
+				return DeidentifyDummy
+			}
+			return DeidentifyZero
+		}
+		return DeidentifyRemove
+	}
+
+	return action
+}
+
+func (d *deidentifier) hasOption(option DeidentifyOption) bool {
+	for _, o := range d.options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Replace the UIDs of an element, where the same UID always gets the same replacement
+func (d *deidentifier) replaceUIDs(e *dicom.Element) error {
+	for i, v := range e.Value {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		uid := strings.TrimRight(s, "\x00 ")
+		if uid == "" {
+			continue
+		}
+		if _, ok := d.schema.LookupUID(uid); ok {
+			// The UIDs of the standard (e.g. well-known frames of reference) don't identify anything
+			continue
+		}
+
+		r, ok := d.uids[uid]
+		if !ok {
+			var err error
+			if r, err = d.replace(uid); err != nil {
+				return fmt.Errorf("Unable to replace UID %s of %s: %v", uid, e.Tag, err)
+			}
+			d.uids[uid] = r
+		}
+		e.Value[i] = r
+	}
+	return nil
+}
+
+// Replace the values of an element with their pseudonyms
+func (d *deidentifier) pseudonyms(e *dicom.Element) error {
+	for i, v := range e.Value {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		p, err := d.pseudonymize(e.Tag, s)
+		if err != nil {
+			return fmt.Errorf("Unable to pseudonymize %s: %v", e.Tag, err)
+		}
+		e.Value[i] = p
+	}
+	return nil
+}
+
+// A dummy value for a VR that doesn't resemble the original value
+func dummyValue(vr string) interface{} {
+	switch vr {
+	case "DA":
+		return "19000101"
+	case "TM":
+		return "000000"
+	case "DT":
+		return "19000101000000"
+	case "AS":
+		return "000D"
+	case "DS", "IS":
+		return "0"
+	case "US":
+		return uint16(0)
+	case "SS":
+		return int16(0)
+	case "UL":
+		return uint32(0)
+	case "SL":
+		return int32(0)
+	case "FL":
+		return float32(0)
+	case "FD":
+		return float64(0)
+	case "OB", "OD", "OF", "OL", "OW", "UN":
+		return []byte{0, 0}
+	}
+	return "ANONYMIZED"
+}
+
+func methodCodeItem(code Code) *dicom.Element {
+	return &dicom.Element{Tag: dicomtag.Item, VR: "NA", Value: []interface{}{
+		&dicom.Element{Tag: dicomtag.CodeValue, VR: "SH", Value: []interface{}{code.CodeValue}},
+		&dicom.Element{Tag: dicomtag.CodingSchemeDesignator, VR: "SH", Value: []interface{}{code.CodingSchemeDesignator}},
+		&dicom.Element{Tag: dicomtag.CodeMeaning, VR: "LO", Value: []interface{}{code.CodeMeaning}},
+	}}
+}
+
+// The normalized form of a tag path for comparisons with TagUsage.Path
+func pathKey(path []string) (string, error) {
+	tags := make([]string, len(path))
+	for i, p := range path {
+		t, err := ParseTag(p)
+		if err != nil {
+			return "", err
+		}
+		tags[i] = t.String()
+	}
+	return strings.Join(tags, ">"), nil
+}
@@ -0,0 +1,269 @@
+package dicom
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+func init() {
+	RegisterPrivateDictionary(PrivateDictionary{
+		Creator: "DEIDENTIFY TEST",
+		TagDefs: map[string]TagDef{
+			"(0009,xx01)": {Keyword: "SafeValue", VR: []string{"LO"}, Deidentify: "K"},
+			"(0009,xx02)": {Keyword: "UnsafeValue", VR: []string{"LO"}},
+		},
+	})
+}
+
+const testSOPClassUID = "1.2.3.4"
+
+// A schema with one IOD whose attributes have the different Types of the compound actions
+func testDeidentifySchema() *SchemaDef {
+	usage := func(typ string, tags ...dicomtag.Tag) TagUsage {
+		path := make([]string, len(tags))
+		for i, t := range tags {
+			path[i] = t.String()
+		}
+		return TagUsage{Path: path, Type: typ}
+	}
+	tagDef := func(keyword, vr, action string) TagDef {
+		return TagDef{Keyword: keyword, VR: []string{vr}, Deidentify: action}
+	}
+
+	return &SchemaDef{
+		ClassDefs: []ClassDef{{SOPClassUid: testSOPClassUID, Name: "Test Image Storage", Modules: []ModuleUsage{{Name: "Test", Usage: "M"}}}},
+		ModuleDefs: map[string]ModuleDef{
+			"Test": {Tags: []TagUsage{
+				usage("1", dicomtag.SOPClassUID),
+				usage("1", dicomtag.SOPInstanceUID),
+				usage("2", dicomtag.PatientName),
+				usage("1", dicomtag.PatientID),
+				usage("2", dicomtag.StudyDate),
+				usage("3", dicomtag.InstitutionName),
+				usage("1", dicomtag.DeviceSerialNumber),
+				usage("2", dicomtag.StationName),
+				usage("3", dicomtag.StudyDescription),
+				usage("1", dicomtag.ReferencedImageSequence),
+				usage("1", dicomtag.ReferencedImageSequence, dicomtag.ReferencedSOPClassUID),
+				usage("1", dicomtag.ReferencedImageSequence, dicomtag.ReferencedSOPInstanceUID),
+				usage("1", dicomtag.FrameOfReferenceUID),
+			}},
+		},
+		TagDefs: map[string]TagDef{
+			"(0008,0016)": tagDef("SOPClassUID", "UI", ""),
+			"(0008,0018)": tagDef("SOPInstanceUID", "UI", "U"),
+			"(0010,0010)": tagDef("PatientName", "PN", "Z"),
+			"(0010,0020)": tagDef("PatientID", "LO", "Z"),
+			"(0008,0020)": tagDef("StudyDate", "DA", "Z"),
+			"(0008,0080)": tagDef("InstitutionName", "LO", "X/Z/D"),
+			"(0018,1000)": tagDef("DeviceSerialNumber", "LO", "X/Z/D"),
+			"(0008,1010)": tagDef("StationName", "SH", "X/Z/D"),
+			"(0008,1030)": tagDef("StudyDescription", "LO", "X"),
+			"(0008,1140)": tagDef("ReferencedImageSequence", "SQ", "X/Z/U*"),
+			"(0008,1150)": tagDef("ReferencedSOPClassUID", "UI", "U"),
+			"(0008,1155)": tagDef("ReferencedSOPInstanceUID", "UI", "U"),
+			"(0020,0052)": tagDef("FrameOfReferenceUID", "UI", "U"),
+		},
+		// The SOP Class of the IOD is in the UID registry of the schema, so it is never replaced
+		UIDs: map[string]UIDDef{testSOPClassUID: {Name: "Test Image Storage", Type: UIDTypeSOPClass}},
+	}
+}
+
+func testDeidentifyDataSet() *dicom.DataSet {
+	str := func(t dicomtag.Tag, vr string, values ...interface{}) *dicom.Element {
+		return &dicom.Element{Tag: t, VR: vr, Value: values}
+	}
+	private := func(element uint16, vr string, values ...interface{}) *dicom.Element {
+		return str(dicomtag.Tag{Group: 0x0009, Element: element}, vr, values...)
+	}
+
+	return &dicom.DataSet{Elements: []*dicom.Element{
+		str(dicomtag.MediaStorageSOPInstanceUID, "UI", "1.2.3.4.5"),
+		str(dicomtag.TransferSyntaxUID, "UI", "1.2.840.10008.1.2.1"),
+		str(dicomtag.SourceApplicationEntityTitle, "AE", "HOSPITAL_PACS"),
+		str(dicomtag.Tag{Group: 0x0008, Element: 0x0000}, "UL", uint32(100)),
+		str(dicomtag.SOPClassUID, "UI", testSOPClassUID),
+		str(dicomtag.SOPInstanceUID, "UI", "1.2.3.4.5"),
+		str(dicomtag.StudyDate, "DA", "20190315"),
+		str(dicomtag.InstitutionName, "LO", "General Hospital"),
+		str(dicomtag.StationName, "SH", "CT01"),
+		str(dicomtag.StudyDescription, "LO", "Chest CT of John Doe"),
+		str(dicomtag.ReferencedImageSequence, "SQ", &dicom.Element{Tag: dicomtag.Item, VR: "NA", Value: []interface{}{
+			str(dicomtag.ReferencedSOPClassUID, "UI", testSOPClassUID),
+			str(dicomtag.ReferencedSOPInstanceUID, "UI", "1.2.3.4.5"),
+		}}),
+		private(0x0010, "LO", "DEIDENTIFY TEST"),
+		private(0x1001, "LO", "safe"),
+		private(0x1002, "LO", "unsafe"),
+		private(0x0011, "LO", "UNKNOWN CREATOR"),
+		private(0x1101, "LO", "unknown"),
+		str(dicomtag.PatientName, "PN", "Doe^John"),
+		str(dicomtag.PatientID, "LO", "12345"),
+		str(dicomtag.DeviceSerialNumber, "LO", "SN-1"),
+		str(dicomtag.FrameOfReferenceUID, "UI", "1.2.840.10008.1.4.1.1"),
+		// Not in the schema
+		str(dicomtag.Tag{Group: 0x0010, Element: 0x2203}, "CS", "MALE"),
+	}}
+}
+
+func TestDeidentifyActions(t *testing.T) {
+	tests := []struct {
+		tag     dicomtag.Tag
+		options []DeidentifyOption
+		// The values that are expected, or nil if the element is expected to be removed
+		want []interface{}
+	}{
+		// "Z" is empty for Type 2 and a dummy value for Type 1
+		{dicomtag.PatientName, nil, []interface{}{}},
+		{dicomtag.PatientID, nil, []interface{}{"ANONYMIZED"}},
+		{dicomtag.StudyDate, nil, []interface{}{}},
+		// "X/Z/D" is a dummy value for Type 1, empty for Type 2 and removed otherwise
+		{dicomtag.DeviceSerialNumber, nil, []interface{}{"ANONYMIZED"}},
+		{dicomtag.StationName, nil, []interface{}{}},
+		{dicomtag.InstitutionName, nil, nil},
+		{dicomtag.StudyDescription, nil, nil},
+		// The attributes without an action and the registered UIDs are kept
+		{dicomtag.SOPClassUID, nil, []interface{}{testSOPClassUID}},
+		// The attributes that aren't in the schema are removed, except the encoding of the file
+		{dicomtag.Tag{Group: 0x0010, Element: 0x2203}, nil, nil},
+		{dicomtag.TransferSyntaxUID, nil, []interface{}{"1.2.840.10008.1.2.1"}},
+		{dicomtag.SourceApplicationEntityTitle, nil, nil},
+		{dicomtag.Tag{Group: 0x0008, Element: 0x0000}, nil, nil},
+		// Private attributes are removed, unless they are safe with the Retain Safe Private option
+		{dicomtag.Tag{Group: 0x0009, Element: 0x0010}, nil, nil},
+		{dicomtag.Tag{Group: 0x0009, Element: 0x1001}, nil, nil},
+		{dicomtag.Tag{Group: 0x0009, Element: 0x0010}, []DeidentifyOption{RetainSafePrivateOption}, []interface{}{"DEIDENTIFY TEST"}},
+		{dicomtag.Tag{Group: 0x0009, Element: 0x1001}, []DeidentifyOption{RetainSafePrivateOption}, []interface{}{"safe"}},
+		{dicomtag.Tag{Group: 0x0009, Element: 0x1002}, []DeidentifyOption{RetainSafePrivateOption}, nil},
+		{dicomtag.Tag{Group: 0x0009, Element: 0x0011}, []DeidentifyOption{RetainSafePrivateOption}, nil},
+		{dicomtag.Tag{Group: 0x0009, Element: 0x1101}, []DeidentifyOption{RetainSafePrivateOption}, nil},
+		// The identity of the de-identification is recorded
+		{dicomtag.PatientIdentityRemoved, nil, []interface{}{"YES"}},
+		{dicomtag.DeidentificationMethod, nil, []interface{}{"Basic Application Confidentiality Profile"}},
+		{dicomtag.DeidentificationMethod, []DeidentifyOption{RetainSafePrivateOption}, []interface{}{"Basic Application Confidentiality Profile", "Retain Safe Private Option"}},
+	}
+
+	for _, tt := range tests {
+		ds := testDeidentifyDataSet()
+		if err := Deidentify(ds, testDeidentifySchema(), DeidentifyOptions{Options: tt.options}); err != nil {
+			t.Errorf("Deidentify(%v) error: %v", tt.options, err)
+			continue
+		}
+
+		e, err := ds.FindElementByTag(tt.tag)
+		switch {
+		case tt.want == nil && err == nil:
+			t.Errorf("Deidentify(%v) kept %v = %v, want it removed", tt.options, tt.tag, e.Value)
+		case tt.want != nil && err != nil:
+			t.Errorf("Deidentify(%v) removed %v, want %v", tt.options, tt.tag, tt.want)
+		case tt.want != nil && !reflect.DeepEqual(e.Value, tt.want):
+			t.Errorf("Deidentify(%v) %v = %#v, want %#v", tt.options, tt.tag, e.Value, tt.want)
+		}
+	}
+}
+
+func TestDeidentifyUIDs(t *testing.T) {
+	n := 0
+	replace := func(uid string) (string, error) {
+		n++
+		return fmt.Sprintf("2.25.%d", n), nil
+	}
+
+	ds := testDeidentifyDataSet()
+	if err := Deidentify(ds, testDeidentifySchema(), DeidentifyOptions{ReplaceUID: replace}); err != nil {
+		t.Fatalf("Deidentify error: %v", err)
+	}
+
+	value := func(elems []*dicom.Element, tag dicomtag.Tag) string {
+		e, err := dicom.FindElementByTag(elems, tag)
+		if err != nil {
+			t.Fatalf("Deidentify removed %v", tag)
+		}
+		s, _ := e.GetString()
+		return s
+	}
+
+	// The same UID gets the same replacement everywhere, including the Media Storage SOP Instance
+	// UID and the references in sequences
+	uid := value(ds.Elements, dicomtag.SOPInstanceUID)
+	if uid == "1.2.3.4.5" || !strings.HasPrefix(uid, "2.25.") {
+		t.Errorf("SOPInstanceUID = %q, want a replacement", uid)
+	}
+	if got := value(ds.Elements, dicomtag.MediaStorageSOPInstanceUID); got != uid {
+		t.Errorf("MediaStorageSOPInstanceUID = %q, want %q", got, uid)
+	}
+
+	seq, err := ds.FindElementByTag(dicomtag.ReferencedImageSequence)
+	if err != nil || len(seq.Value) != 1 {
+		t.Fatalf("ReferencedImageSequence was not kept with its item")
+	}
+	item := itemElements(seq.Value[0].(*dicom.Element))
+	if got := value(item, dicomtag.ReferencedSOPInstanceUID); got != uid {
+		t.Errorf("ReferencedSOPInstanceUID = %q, want %q", got, uid)
+	}
+
+	// The UIDs of the UID registry of the schema are kept, others are replaced even if they are
+	// in the registry of another schema
+	if got := value(item, dicomtag.ReferencedSOPClassUID); got != testSOPClassUID {
+		t.Errorf("ReferencedSOPClassUID = %q, want %q", got, testSOPClassUID)
+	}
+	if got := value(ds.Elements, dicomtag.FrameOfReferenceUID); got == "1.2.840.10008.1.4.1.1" {
+		t.Errorf("FrameOfReferenceUID = %q, want a replacement", got)
+	}
+
+	if n != 2 {
+		t.Errorf("ReplaceUID was called %d times, want 2", n)
+	}
+}
+
+func TestDeidentifyModifiedDates(t *testing.T) {
+	opts := DeidentifyOptions{
+		Options:   []DeidentifyOption{RetainLongitudinalModifiedDatesOption},
+		DateShift: func(string) (int, error) { return -10, nil },
+	}
+
+	schema := testDeidentifySchema()
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"20190315", "20190305"},
+		// The values of a multi-valued date are each shifted
+		{"20190315\\20190401", "20190305\\20190322"},
+		// Invalid dates get a dummy value
+		{"2019-03-15", "19000101"},
+	}
+
+	for _, tt := range tests {
+		ds := testDeidentifyDataSet()
+		e, _ := ds.FindElementByTag(dicomtag.StudyDate)
+		e.Value = []interface{}{tt.value}
+
+		if err := Deidentify(ds, schema, opts); err != nil {
+			t.Fatalf("Deidentify error: %v", err)
+		}
+		if e, err := ds.FindElementByTag(dicomtag.StudyDate); err != nil {
+			t.Errorf("Deidentify removed StudyDate %q", tt.value)
+		} else if !reflect.DeepEqual(e.Value, []interface{}{tt.want}) {
+			t.Errorf("StudyDate %q = %v, want [%s]", tt.value, e.Value, tt.want)
+		}
+	}
+
+	opts.DateShift = nil
+	if err := Deidentify(testDeidentifyDataSet(), schema, opts); err == nil {
+		t.Errorf("Deidentify without a DateShift succeeded, want an error")
+	}
+}
+
+func TestDeidentifyUnsupportedOption(t *testing.T) {
+	opts := DeidentifyOptions{Options: []DeidentifyOption{"Retain Device Identity"}}
+	if err := Deidentify(testDeidentifyDataSet(), testDeidentifySchema(), opts); err == nil {
+		t.Errorf("Deidentify with an unsupported option succeeded, want an error")
+	}
+}
@@ -1,12 +1,16 @@
 package dicom
 
+import "encoding"
 import "encoding/json"
 import "fmt"
 import "github.com/macadamian/dicom/dicom2019b"
+import "github.com/macadamian/dicom/dicomvalue"
 import "github.com/gradienthealth/dicom"
 import "github.com/gradienthealth/dicom/dicomtag"
 import "os"
 import "reflect"
+import "strings"
+import "time"
 
 func main() {
 	inst := dicom2019b.MRImageStorage{}
@@ -63,13 +67,136 @@ func main() {
 //     ...
 //   }
 //
+// Sequence elements are unmarshaled into slices of the sequence structs, with each item of the
+// sequence unmarshaled into a new struct value. This is done recursively for sequences that are
+// nested at any depth.
+//
+// String values are converted when the field has another type. Fields with a type that implements
+// encoding.TextUnmarshaler, such as the dicomvalue Date, Time, DateTime, PersonName and Age, are
+// decoded from the string. Integer and floating point fields are parsed as IS and DS values. The
+// dates and times without an offset of their own are given the Timezone Offset From UTC (0008,0201)
+// of the dataset as their location, if it is present.
+//
 // Note that any extra DICOM tags that don't fit within the schema structure of the storage and
 // related types are ignored and remain in the original dataset along with all of the other tags
-// that did match.
+// that did match. Use UnmarshalOptions to get a report of these elements and other problems
+// found while unmarshaling. Private tags that are in a registered private dictionary (see
+// RegisterPrivateDictionary), and their Private Creator elements, are expected to be extra and
+// are not reported.
 func Unmarshal(ds *dicom.DataSet, v interface{}) error {
+	_, err := UnmarshalOptions{}.Unmarshal(ds, v)
+	return err
+}
+
+// UnmarshalAny unmarshals a DICOM dataset into a new value of the dicom2019b storage class that
+// matches its SOPClassUID. The result is a pointer to the storage class struct
+// (e.g. *dicom2019b.MRImageStorage) that can be used with a type switch.
+func UnmarshalAny(ds *dicom.DataSet) (interface{}, error) {
+	v, _, err := UnmarshalOptions{}.UnmarshalAny(ds)
+	return v, err
+}
+
+// UnmarshalMode determines how problems found while unmarshaling are handled.
+type UnmarshalMode int
+
+const (
+	// Lenient mode reports the problems found while unmarshaling without failing.
+	Lenient UnmarshalMode = iota
+	// Strict mode fails with an *UnmarshalError if any problems are found while unmarshaling.
+	Strict
+)
+
+// UnmarshalOptions configures the unmarshaling of a DICOM dataset into a Go value.
+type UnmarshalOptions struct {
+	Mode UnmarshalMode
+}
+
+// UnmarshalIssueKind classifies a problem found while unmarshaling.
+type UnmarshalIssueKind string
+
+const (
+	// UnmatchedElement is an element that has no matching field in the storage or sequence struct.
+	UnmatchedElement UnmarshalIssueKind = "UnmatchedElement"
+	// ConversionFailure is an element with values that can't be assigned to the type of its field.
+	ConversionFailure UnmarshalIssueKind = "ConversionFailure"
+	// DuplicateAssignment is an element that was assigned a different value than a field of another
+	// module with the same tag, or that repeats the tag of an earlier element with another value.
+	// The attributes that are in more than one module of a storage class (e.g. InstanceNumber in
+	// GeneralImage and SOPCommon) are expected to be assigned to each of them.
+	DuplicateAssignment UnmarshalIssueKind = "DuplicateAssignment"
+)
+
+// An UnmarshalIssue describes a single problem found while unmarshaling an element.
+type UnmarshalIssue struct {
+	Kind UnmarshalIssueKind
+	Tag  dicomtag.Tag
+	// Keyword is the keyword of the tag from the standard dictionary, if it is known.
+	Keyword string
+	// Module is the name of the module or sequence struct, if any, where the issue was found.
+	Module string
+	// Path is the list of sequence tags that contain the element, empty for top-level elements.
+	Path    []dicomtag.Tag
+	Message string
+}
+
+func (ui UnmarshalIssue) String() string {
+	s := string(ui.Kind)
+	if ui.Module != "" {
+		s += " [" + ui.Module + "]"
+	}
+	s += " "
+	for _, t := range ui.Path {
+		s += t.String() + ">"
+	}
+	s += ui.Tag.String()
+	if ui.Keyword != "" {
+		s += " " + ui.Keyword
+	}
+	if ui.Message != "" {
+		s += ": " + ui.Message
+	}
+	return s
+}
+
+// An UnmarshalReport lists the problems found while unmarshaling.
+type UnmarshalReport struct {
+	Issues []UnmarshalIssue
+}
+
+func (r *UnmarshalReport) add(kind UnmarshalIssueKind, e *dicom.Element, module string, path []dicomtag.Tag, message string) {
+	keyword := ""
+	if ti, err := dicomtag.Find(e.Tag); err == nil {
+		keyword = ti.Name
+	}
+
+	r.Issues = append(r.Issues, UnmarshalIssue{
+		Kind:    kind,
+		Tag:     e.Tag,
+		Keyword: keyword,
+		Module:  module,
+		Path:    append([]dicomtag.Tag{}, path...),
+		Message: message,
+	})
+}
+
+// An UnmarshalError is returned in Strict mode when problems are found while unmarshaling.
+type UnmarshalError struct {
+	Report UnmarshalReport
+}
+
+func (ue *UnmarshalError) Error() string {
+	return fmt.Sprintf("Unable to strictly unmarshal the dataset, %d issue(s) found, first is %s", len(ue.Report.Issues), ue.Report.Issues[0])
+}
+
+// Unmarshal a DICOM dataset into a provided Go value like Unmarshal and provide a report of the
+// elements that couldn't be matched or assigned to a field, and of the elements with conflicting
+// assignments (see DuplicateAssignment). In Strict mode, any of these issues results in an *UnmarshalError.
+func (o UnmarshalOptions) Unmarshal(ds *dicom.DataSet, v interface{}) (UnmarshalReport, error) {
+	report := UnmarshalReport{}
+
 	pt := reflect.TypeOf(v)
-	if pt.Kind() != reflect.Ptr {
-		return fmt.Errorf("Must provide a pointer to a storage value to do anything meaningful")
+	if pt == nil || pt.Kind() != reflect.Ptr {
+		return report, fmt.Errorf("Must provide a pointer to a storage value to do anything meaningful")
 	}
 
 	pv := reflect.ValueOf(v)
@@ -77,30 +204,42 @@ func Unmarshal(ds *dicom.DataSet, v interface{}) error {
 	sct := scv.Type()
 
 	if sct.Kind() != reflect.Struct {
-		return fmt.Errorf("Must provide a pointer to a storage class struct to do anything meaningful")
+		return report, fmt.Errorf("Must provide a pointer to a storage class struct to do anything meaningful")
 	}
 
 	soif, ok := sct.FieldByName("SOPClassUID")
 
 	if !ok {
-		return fmt.Errorf("Provided struct is not a storage class struct. It is missing the SOPInstanceUID property")
+		return report, fmt.Errorf("Provided struct is not a storage class struct. It is missing the SOPInstanceUID property")
 	}
 
 	expectedSOPClass := soif.Tag
 
 	sce, err := ds.FindElementByTag(dicomtag.SOPClassUID)
 	if err != nil {
-		return err
+		return report, err
 	}
 
-	if len(sce.Value) != 1 || sce.Value[0] == expectedSOPClass {
-		return fmt.Errorf("Expected Storage Class UID to be %s, but was %v.\n", expectedSOPClass, sce)
+	if len(sce.Value) != 1 || sce.Value[0] != string(expectedSOPClass) {
+		return report, fmt.Errorf("Expected Storage Class UID to be %s, but was %v.\n", expectedSOPClass, sce)
 	}
 
+	loc := datasetLocation(ds)
+	assigned := map[dicomtag.Tag]*dicom.Element{}
+
 	for _, e := range ds.Elements {
-		hit := false
+		if prev, ok := assigned[e.Tag]; ok && !reflect.DeepEqual(prev.Value, e.Value) {
+			report.add(DuplicateAssignment, e, "", nil, "Repeats an earlier element with another value")
+		}
+		assigned[e.Tag] = e
+
+		hit := ""
+		// The values of the first field that was assigned, and its module, to compare the other
+		// fields against
+		var hitValues []interface{}
+		hitm := ""
 		modfn := sct.NumField()
-		for i := 0 ; i < modfn; i++ {
+		for i := 0; i < modfn; i++ {
 			modf := sct.Field(i)
 			modt := modf.Type
 
@@ -111,10 +250,15 @@ func Unmarshal(ds *dicom.DataSet, v interface{}) error {
 
 			if modt.Kind() == reflect.Ptr {
 				modt = modt.Elem()
-				modv = reflect.New(modt)
-				addModule = func() {
-					scv.Field(i).Set(modv)
-					modv = modv.Elem()
+				if scv.Field(i).IsNil() {
+					modv = reflect.New(modt)
+					addModule = func() {
+						scv.Field(i).Set(modv)
+						modv = modv.Elem()
+					}
+				} else {
+					// The module was already added for an earlier element
+					modv = scv.Field(i).Elem()
 				}
 			} else {
 				modv = scv.Field(i)
@@ -123,7 +267,7 @@ func Unmarshal(ds *dicom.DataSet, v interface{}) error {
 			if modt.Kind() != reflect.Struct {
 				continue
 			}
-			
+
 			fn := modt.NumField()
 			for j := 0; j < fn; j++ {
 				tagf := modt.Field(j)
@@ -135,55 +279,298 @@ func Unmarshal(ds *dicom.DataSet, v interface{}) error {
 
 					tagv := modv.Field(j)
 
-					//fmt.Printf("Assign this value: %d %+v to %v\n", len(e.Value), e.Value, tagf)
-
 					if !modv.Field(j).CanSet() {
-						return fmt.Errorf("Can't set %v in %v\n", modt.Name(), modt.Field(j))
+						return report, fmt.Errorf("Can't set %v in %v\n", modt.Name(), modt.Field(j))
 					}
 
-					// Simple individual assignment
-					if len(e.Value) == 1 && tagf.Type == reflect.TypeOf(e.Value[0]) {
-						tagv.Set(reflect.ValueOf(e.Value[0]))
-					} else if len(e.Value) == 1 && tagf.Type.Kind() == reflect.Ptr && tagf.Type.Elem() == reflect.TypeOf(e.Value[0]) {
-						// Temporary slice of the correct type so that we can get an address of the value
-						s := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(e.Value[0])), 0, 1)
-						s = reflect.Append(s, reflect.ValueOf(e.Value[0]))
-						tagv.Set(s.Index(0).Addr())
-					} else if len(e.Value) == 0 {
-						// This is fine, nothing to assign
-					} else if tagf.Type.Kind() == reflect.Array {
-						for k := 0; k < tagf.Type.Len() && k < len(e.Value); k++ {
-							tagv.Index(k).Set(reflect.ValueOf(e.Value[k]))
-						}
-					} else if tagf.Type.Kind() == reflect.Ptr && tagf.Type.Elem().Kind() == reflect.Array {
-						av := reflect.New(reflect.ArrayOf(tagf.Type.Elem().Len(), tagf.Type.Elem().Elem()))
-						for k := 0; k < tagf.Type.Elem().Len() && k < len(e.Value); k++ {
-							av.Elem().Index(k).Set(reflect.ValueOf(e.Value[k]))
-						}
-						tagv.Set(av)
-					} else if tagf.Type.Kind() == reflect.Slice && tagf.Type.Elem().Kind() != reflect.Struct {
-						sv := reflect.MakeSlice(reflect.SliceOf(tagf.Type.Elem()), 0, 0)
-						for _, v := range e.Value {
-							sv = reflect.Append(sv, reflect.ValueOf(v))
-						}
-						tagv.Set(sv)
-					} else {
-						fmt.Printf("FIELD: %+v VALUE: %+v\n", tagf.Type, reflect.TypeOf(e.Value))
-						fmt.Printf("Could not assign %+v to %+v\n", e, modt.Field(j))
+					if hit == "" {
+						hit = modt.Name()
 					}
-					
-					if hit {
-						fmt.Printf("DUPLICATED: %s\n", e)
+
+					if err := assignElement(tagv, e, &report, nil, loc); err != nil {
+						report.add(ConversionFailure, e, modt.Name(), nil, err.Error())
+					} else if values := fieldValues(e.Tag, tagf.Tag.Get("vr"), tagv); hitm == "" {
+						hitValues, hitm = values, modt.Name()
+					} else if !reflect.DeepEqual(hitValues, values) {
+						report.add(DuplicateAssignment, e, modt.Name(), nil, fmt.Sprintf("Assigned another value in %s", hitm))
 					}
-					hit = true
 				}
 			}
 		}
 
-		if !hit {
-			fmt.Printf("MISS: %v\n", e)
+		if hit == "" && !registeredPrivate(ds.Elements, e.Tag) {
+			report.add(UnmatchedElement, e, "", nil, "")
+		}
+	}
+
+	if o.Mode == Strict && len(report.Issues) > 0 {
+		return report, &UnmarshalError{Report: report}
+	}
+
+	return report, nil
+}
+
+// The values of a field in the form written by Marshal, so that fields of different types with
+// the same tag can be compared (e.g. a string and a number for an IS value)
+func fieldValues(t dicomtag.Tag, vr string, v reflect.Value) []interface{} {
+	e, err := marshalElement(t, vr, v)
+	if err != nil || e == nil {
+		return nil
+	}
+	return e.Value
+}
+
+// UnmarshalAny is like the UnmarshalAny function, but also provides the report of the problems
+// found while unmarshaling.
+func (o UnmarshalOptions) UnmarshalAny(ds *dicom.DataSet) (interface{}, UnmarshalReport, error) {
+	sce, err := ds.FindElementByTag(dicomtag.SOPClassUID)
+	if err != nil {
+		return nil, UnmarshalReport{}, err
+	}
+
+	uid, err := sce.GetString()
+	if err != nil {
+		return nil, UnmarshalReport{}, err
+	}
+
+	newStorage, ok := dicom2019b.StorageClasses[uid]
+	if !ok {
+		return nil, UnmarshalReport{}, fmt.Errorf("There is no storage class for SOP Class UID %s", uid)
+	}
+
+	v := newStorage()
+	report, err := o.Unmarshal(ds, v)
+	if err != nil {
+		return nil, report, err
+	}
+
+	return v, report, nil
+}
+
+// Assign the values of an element to a field of a module or sequence struct. Sequences are
+// assigned recursively into slices of the sequence structs with any issues within them added
+// to the report. The path is the list of sequence tags that contain the element.
+func assignElement(tagv reflect.Value, e *dicom.Element, report *UnmarshalReport, path []dicomtag.Tag, loc *time.Location) error {
+	tagt := tagv.Type()
+
+	values, err := convertValues(e, valueType(tagt), loc)
+	if err != nil {
+		return err
+	}
+
+	// Simple individual assignment
+	if len(values) == 1 && tagt == reflect.TypeOf(values[0]) {
+		tagv.Set(reflect.ValueOf(values[0]))
+	} else if len(values) == 1 && tagt.Kind() == reflect.Ptr && tagt.Elem() == reflect.TypeOf(values[0]) {
+		// Temporary slice of the correct type so that we can get an address of the value
+		s := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(values[0])), 0, 1)
+		s = reflect.Append(s, reflect.ValueOf(values[0]))
+		tagv.Set(s.Index(0).Addr())
+	} else if len(values) == 0 {
+		// This is fine, nothing to assign
+	} else if tagt.Kind() == reflect.Array {
+		for k := 0; k < tagt.Len() && k < len(values); k++ {
+			if err := assignValue(tagv.Index(k), values[k]); err != nil {
+				return err
+			}
+		}
+	} else if tagt.Kind() == reflect.Ptr && tagt.Elem().Kind() == reflect.Array {
+		av := reflect.New(reflect.ArrayOf(tagt.Elem().Len(), tagt.Elem().Elem()))
+		for k := 0; k < tagt.Elem().Len() && k < len(values); k++ {
+			if err := assignValue(av.Elem().Index(k), values[k]); err != nil {
+				return err
+			}
+		}
+		tagv.Set(av)
+	} else if tagt.Kind() == reflect.Slice && tagt.Elem().Kind() == reflect.Struct && e.VR == "SQ" {
+		sv := reflect.MakeSlice(tagt, 0, len(values))
+		for _, v := range values {
+			item, ok := v.(*dicom.Element)
+			if !ok || item.Tag != dicomtag.Item {
+				return fmt.Errorf("Expected sequence items for %v, but found %T", tagt, v)
+			}
+
+			itemv := reflect.New(tagt.Elem()).Elem()
+			unmarshalItem(item, itemv, report, append(append([]dicomtag.Tag{}, path...), e.Tag), loc)
+			sv = reflect.Append(sv, itemv)
+		}
+		tagv.Set(sv)
+	} else if tagt.Kind() == reflect.Slice {
+		sv := reflect.MakeSlice(reflect.SliceOf(tagt.Elem()), len(values), len(values))
+		for k, v := range values {
+			if err := assignValue(sv.Index(k), v); err != nil {
+				return err
+			}
+		}
+		tagv.Set(sv)
+	} else {
+		return fmt.Errorf("Could not assign %d value(s) of type %T to %v", len(values), values[0], tagt)
+	}
+
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// The type of the individual values of a field, without the pointer, array or slice around them
+func valueType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Array || (t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8) {
+		t = t.Elem()
+	}
+	return t
+}
+
+// Convert the string values of an element to the type of the values of the field that they are
+// assigned to. Values that are not strings, or that are assigned to string fields, are unchanged
+// except that multiple DA values are split since the parser provides them as a single string.
+func convertValues(e *dicom.Element, typ reflect.Type, loc *time.Location) ([]interface{}, error) {
+	values := e.Value
+	if e.VR == "DA" {
+		values = []interface{}{}
+		for _, v := range e.Value {
+			if s, ok := v.(string); ok && strings.Contains(s, "\\") {
+				for _, ds := range strings.Split(s, "\\") {
+					values = append(values, ds)
+				}
+				continue
+			}
+			values = append(values, v)
+		}
+	}
+
+	if typ == reflect.TypeOf("") {
+		return values, nil
+	}
+
+	converted := make([]interface{}, len(values))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			converted[i] = v
+			continue
+		}
+
+		cv, err := convertString(s, typ)
+		if err == dicomvalue.ErrEmpty {
+			// An empty number is left unassigned, or is a zero in a list of numbers
+			if len(values) == 1 {
+				return []interface{}{}, nil
+			}
+			cv = reflect.Zero(typ).Interface()
+		} else if err != nil {
+			return nil, err
+		}
+		converted[i] = withDefaultLocation(cv, loc)
+	}
+
+	return converted, nil
+}
+
+// Convert a single string value to a type that has a text form or to a number
+func convertString(s string, typ reflect.Type) (interface{}, error) {
+	pv := reflect.New(typ)
+
+	switch {
+	case reflect.PtrTo(typ).Implements(textUnmarshalerType):
+		if err := pv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return nil, err
 		}
+	case typ.Kind() == reflect.String:
+		pv.Elem().SetString(s)
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Int64:
+		n, err := dicomvalue.ParseIS(s)
+		if err != nil {
+			return nil, err
+		}
+		if pv.Elem().OverflowInt(n) {
+			return nil, fmt.Errorf("Integer string %q is out of range for %v", s, typ)
+		}
+		pv.Elem().SetInt(n)
+	case typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64:
+		f, err := dicomvalue.ParseDS(s)
+		if err != nil {
+			return nil, err
+		}
+		pv.Elem().SetFloat(f)
+	default:
+		return s, nil
+	}
+
+	return pv.Elem().Interface(), nil
+}
+
+// Give dates and times without an offset of their own the location of the dataset
+func withDefaultLocation(v interface{}, loc *time.Location) interface{} {
+	if loc == nil {
+		return v
+	}
+
+	switch dv := v.(type) {
+	case dicomvalue.Date:
+		return dv.WithDefaultLocation(loc)
+	case dicomvalue.Time:
+		return dv.WithDefaultLocation(loc)
+	case dicomvalue.DateTime:
+		return dv.WithDefaultLocation(loc)
 	}
+	return v
+}
 
+// The location of the Timezone Offset From UTC (0008,0201) of a dataset, or nil if it is not
+// present or not valid.
+func datasetLocation(ds *dicom.DataSet) *time.Location {
+	e, err := ds.FindElementByTag(dicomtag.TimezoneOffsetFromUTC)
+	if err != nil {
+		return nil
+	}
+
+	s, err := e.GetString()
+	if err != nil {
+		return nil
+	}
+
+	loc, err := dicomvalue.ParseOffset(s)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// Assign a single value to an element of an array or slice field
+func assignValue(dst reflect.Value, v interface{}) error {
+	vv := reflect.ValueOf(v)
+	if !vv.IsValid() || !vv.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("Could not assign a value of type %T to %v", v, dst.Type())
+	}
+	dst.Set(vv)
 	return nil
-}
\ No newline at end of file
+}
+
+// Unmarshal the elements of a sequence item into a sequence struct
+func unmarshalItem(item *dicom.Element, itemv reflect.Value, report *UnmarshalReport, path []dicomtag.Tag, loc *time.Location) {
+	itemt := itemv.Type()
+
+	for _, v := range item.Value {
+		e, ok := v.(*dicom.Element)
+		if !ok {
+			continue
+		}
+
+		hit := false
+		for j := 0; j < itemt.NumField(); j++ {
+			tagf := itemt.Field(j)
+
+			if tagf.Tag.Get("tag") == e.Tag.String() {
+				if err := assignElement(itemv.Field(j), e, report, path, loc); err != nil {
+					report.add(ConversionFailure, e, itemt.Name(), path, err.Error())
+				}
+				hit = true
+				break
+			}
+		}
+
+		if !hit && !registeredPrivate(itemElements(item), e.Tag) {
+			report.add(UnmatchedElement, e, itemt.Name(), path, "")
+		}
+	}
+}
@@ -2,6 +2,12 @@
 // Date Assembled: Mon Mar  2 14:00:22 EST 2020
 package dicom2015bdata
 
+import "github.com/macadamian/dicom"
+
+func init() {
+	dicom.RegisterSchema("2015b", func() string { return SchemaStr })
+}
+
 // Unmarshal this string into a github.com/macadamian/dicom SchemaDef using encoding/json package.
 // You can assign an empty string here afterwards to free memory.
 var SchemaStr =`
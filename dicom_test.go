@@ -0,0 +1,188 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+	"github.com/macadamian/dicom/dicom2019b"
+)
+
+// A storage class with a tag in two modules that are assigned as different types
+type testStorage struct {
+	SOPClassUID bool `1.2.3.4`
+	TextModule  testTextModule
+	// An optional module
+	NumberModule *testNumberModule
+}
+
+type testTextModule struct {
+	SOPClassUID    string `tag:"(0008,0016)" vr:"UI" vm:"1"`
+	InstanceNumber string `tag:"(0020,0013)" vr:"IS" vm:"1"`
+}
+
+type testNumberModule struct {
+	InstanceNumber *int64 `tag:"(0020,0013)" vr:"IS" vm:"1"`
+}
+
+func testUnmarshalIssues(report UnmarshalReport) []UnmarshalIssueKind {
+	kinds := []UnmarshalIssueKind{}
+	for _, ui := range report.Issues {
+		kinds = append(kinds, ui.Kind)
+	}
+	return kinds
+}
+
+func TestUnmarshalModes(t *testing.T) {
+	mrds, err := Marshal(testMRImage(t))
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	el := testValidateElement
+
+	tests := []struct {
+		name  string
+		ds    *dicom.DataSet
+		v     interface{}
+		kinds []UnmarshalIssueKind
+	}{
+		// The attributes in more than one module of MR Image Storage, such as InstanceNumber
+		// and BitsAllocated, are assigned to each of them
+		{"MR image", mrds, &dicom2019b.MRImageStorage{}, nil},
+		{"same value", &dicom.DataSet{Elements: []*dicom.Element{
+			el(dicomtag.SOPClassUID, "UI", "1.2.3.4"),
+			el(dicomtag.InstanceNumber, "IS", "7"),
+			el(dicomtag.InstanceNumber, "IS", "7"),
+		}}, &testStorage{}, nil},
+		{"unmatched element", &dicom.DataSet{Elements: []*dicom.Element{
+			el(dicomtag.SOPClassUID, "UI", "1.2.3.4"),
+			el(dicomtag.PatientName, "PN", "Doe^John"),
+		}}, &testStorage{}, []UnmarshalIssueKind{UnmatchedElement}},
+		{"conversion failure", &dicom.DataSet{Elements: []*dicom.Element{
+			el(dicomtag.SOPClassUID, "UI", "1.2.3.4"),
+			el(dicomtag.InstanceNumber, "IS", "seven"),
+		}}, &testStorage{}, []UnmarshalIssueKind{ConversionFailure}},
+		// The text "007" and the number 7 are different values
+		{"conflicting modules", &dicom.DataSet{Elements: []*dicom.Element{
+			el(dicomtag.SOPClassUID, "UI", "1.2.3.4"),
+			el(dicomtag.InstanceNumber, "IS", "007"),
+		}}, &testStorage{}, []UnmarshalIssueKind{DuplicateAssignment}},
+		{"repeated tag", &dicom.DataSet{Elements: []*dicom.Element{
+			el(dicomtag.SOPClassUID, "UI", "1.2.3.4"),
+			el(dicomtag.InstanceNumber, "IS", "7"),
+			el(dicomtag.InstanceNumber, "IS", "8"),
+		}}, &testStorage{}, []UnmarshalIssueKind{DuplicateAssignment}},
+	}
+
+	for _, tt := range tests {
+		report, err := UnmarshalOptions{Mode: Lenient}.Unmarshal(tt.ds, tt.v)
+		if err != nil {
+			t.Errorf("%s: Lenient Unmarshal error: %v", tt.name, err)
+		}
+		kinds := testUnmarshalIssues(report)
+		if len(kinds) != len(tt.kinds) {
+			t.Errorf("%s: Lenient Unmarshal issues = %v, want %v", tt.name, report.Issues, tt.kinds)
+		} else {
+			for i := range kinds {
+				if kinds[i] != tt.kinds[i] {
+					t.Errorf("%s: Lenient Unmarshal issues = %v, want %v", tt.name, report.Issues, tt.kinds)
+					break
+				}
+			}
+		}
+
+		_, err = UnmarshalOptions{Mode: Strict}.Unmarshal(tt.ds, tt.v)
+		if len(tt.kinds) == 0 && err != nil {
+			t.Errorf("%s: Strict Unmarshal error: %v", tt.name, err)
+		}
+		if len(tt.kinds) > 0 {
+			if ue, ok := err.(*UnmarshalError); !ok || ue.Report.Issues[0].Kind != tt.kinds[0] {
+				t.Errorf("%s: Strict Unmarshal error = %v, want an *UnmarshalError with %s", tt.name, err, tt.kinds[0])
+			}
+		}
+	}
+}
+
+func TestUnmarshalOptionalModule(t *testing.T) {
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		testValidateElement(dicomtag.SOPClassUID, "UI", "1.2.3.4"),
+		testValidateElement(dicomtag.InstanceNumber, "IS", "7"),
+	}}
+
+	v := &testStorage{}
+	if err := Unmarshal(ds, v); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if v.TextModule.InstanceNumber != "7" || v.NumberModule == nil || v.NumberModule.InstanceNumber == nil || *v.NumberModule.InstanceNumber != 7 {
+		t.Errorf("Unmarshal = %+v, want InstanceNumber 7 in both modules", v)
+	}
+
+	// The optional module is left out when none of its elements are present
+	v = &testStorage{}
+	if err := Unmarshal(&dicom.DataSet{Elements: ds.Elements[:1]}, v); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if v.NumberModule != nil {
+		t.Errorf("Unmarshal added the NumberModule without any of its elements")
+	}
+}
+
+func TestUnmarshalWrongClass(t *testing.T) {
+	ds := &dicom.DataSet{Elements: []*dicom.Element{testValidateElement(dicomtag.SOPClassUID, "UI", "1.2.3.5")}}
+	if err := Unmarshal(ds, &testStorage{}); err == nil {
+		t.Errorf("Unmarshal of another SOP Class succeeded, want an error")
+	}
+	if err := Unmarshal(ds, testStorage{}); err == nil {
+		t.Errorf("Unmarshal into a struct that isn't a pointer succeeded, want an error")
+	}
+}
+
+func TestUnmarshalAny(t *testing.T) {
+	ds, err := Marshal(testMRImage(t))
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	v, err := UnmarshalAny(ds)
+	if err != nil {
+		t.Fatalf("UnmarshalAny error: %v", err)
+	}
+	switch sc := v.(type) {
+	case *dicom2019b.MRImageStorage:
+		if sc.ImagePixel.Rows != 256 || sc.Patient.PatientID != "12345" {
+			t.Errorf("UnmarshalAny = %+v, want the values of the MR image", sc)
+		}
+	default:
+		t.Errorf("UnmarshalAny = %T, want *dicom2019b.MRImageStorage", v)
+	}
+
+	// The report is provided with the value
+	ds.Elements = append(ds.Elements, testValidateElement(dicomtag.Tag{Group: 0x3006, Element: 0x0002}, "SH", "Structures"))
+	v, report, err := UnmarshalOptions{}.UnmarshalAny(ds)
+	if err != nil || v == nil {
+		t.Fatalf("UnmarshalAny error: %v", err)
+	}
+	if kinds := testUnmarshalIssues(report); len(kinds) != 1 || kinds[0] != UnmatchedElement {
+		t.Errorf("UnmarshalAny issues = %v, want an UnmatchedElement", report.Issues)
+	}
+	if _, _, err := (UnmarshalOptions{Mode: Strict}).UnmarshalAny(ds); err == nil {
+		t.Errorf("Strict UnmarshalAny with an unmatched element succeeded, want an error")
+	}
+}
+
+func TestUnmarshalAnyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		elems []*dicom.Element
+	}{
+		{"no SOP Class", []*dicom.Element{testValidateElement(dicomtag.PatientID, "LO", "12345")}},
+		{"unknown SOP Class", []*dicom.Element{testValidateElement(dicomtag.SOPClassUID, "UI", "1.2.3.4")}},
+		{"SOP Class without a value", []*dicom.Element{testValidateElement(dicomtag.SOPClassUID, "UI")}},
+	}
+
+	for _, tt := range tests {
+		if v, err := UnmarshalAny(&dicom.DataSet{Elements: tt.elems}); err == nil {
+			t.Errorf("%s: UnmarshalAny = %T, want an error", tt.name, v)
+		}
+	}
+}
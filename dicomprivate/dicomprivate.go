@@ -0,0 +1,97 @@
+// Package dicomprivate bundles the private dictionaries of some vendors, which are registered with the
+// github.com/macadamian/dicom package when this package is imported. The dictionaries only have the
+// private tags that are commonly needed, such as the diffusion and scaling parameters of MR images,
+// from the conformance statements of the vendors and the dictionaries of other DICOM toolkits.
+package dicomprivate
+
+import (
+	"encoding/json"
+
+	"github.com/macadamian/dicom"
+)
+
+func init() {
+	dicts := []dicom.PrivateDictionary{}
+	if err := json.Unmarshal([]byte(DictionariesStr), &dicts); err != nil {
+		panic(err)
+	}
+
+	for _, d := range dicts {
+		dicom.RegisterPrivateDictionary(d)
+	}
+}
+
+// Unmarshal this string into a list of github.com/macadamian/dicom PrivateDictionary using encoding/json package.
+var DictionariesStr = `
+[
+	{
+		"Creator": "SIEMENS CSA HEADER",
+		"TagDefs": {
+			"(0029,xx08)": {"Keyword": "CSAImageHeaderType", "VR": ["CS"], "VM": "1"},
+			"(0029,xx09)": {"Keyword": "CSAImageHeaderVersion", "VR": ["LO"], "VM": "1"},
+			"(0029,xx10)": {"Keyword": "CSAImageHeaderInfo", "VR": ["OB"], "VM": "1"},
+			"(0029,xx18)": {"Keyword": "CSASeriesHeaderType", "VR": ["CS"], "VM": "1"},
+			"(0029,xx19)": {"Keyword": "CSASeriesHeaderVersion", "VR": ["LO"], "VM": "1"},
+			"(0029,xx20)": {"Keyword": "CSASeriesHeaderInfo", "VR": ["OB"], "VM": "1"}
+		}
+	},
+	{
+		"Creator": "SIEMENS MR HEADER",
+		"TagDefs": {
+			"(0019,xx08)": {"Keyword": "CSAImageHeaderType", "VR": ["CS"], "VM": "1"},
+			"(0019,xx09)": {"Keyword": "CSAImageHeaderVersion", "VR": ["LO"], "VM": "1"},
+			"(0019,xx0a)": {"Keyword": "NumberOfImagesInMosaic", "VR": ["US"], "VM": "1"},
+			"(0019,xx0b)": {"Keyword": "SliceMeasurementDuration", "VR": ["DS"], "VM": "1"},
+			"(0019,xx0c)": {"Keyword": "BValue", "VR": ["IS"], "VM": "1"},
+			"(0019,xx0d)": {"Keyword": "DiffusionDirectionality", "VR": ["CS"], "VM": "1"},
+			"(0019,xx0e)": {"Keyword": "DiffusionGradientDirection", "VR": ["FD"], "VM": "3"},
+			"(0019,xx0f)": {"Keyword": "GradientMode", "VR": ["SH"], "VM": "1"},
+			"(0019,xx11)": {"Keyword": "FlowCompensation", "VR": ["SH"], "VM": "1"},
+			"(0019,xx12)": {"Keyword": "TablePositionOrigin", "VR": ["SL"], "VM": "3"},
+			"(0019,xx13)": {"Keyword": "ImaAbsTablePosition", "VR": ["SL"], "VM": "3"},
+			"(0019,xx14)": {"Keyword": "ImaRelTablePosition", "VR": ["IS"], "VM": "3"},
+			"(0019,xx15)": {"Keyword": "SlicePositionPCS", "VR": ["FD"], "VM": "3"},
+			"(0019,xx16)": {"Keyword": "TimeAfterStart", "VR": ["DS"], "VM": "1"},
+			"(0019,xx17)": {"Keyword": "SliceResolution", "VR": ["DS"], "VM": "1"},
+			"(0019,xx18)": {"Keyword": "RealDwellTime", "VR": ["IS"], "VM": "1"},
+			"(0019,xx27)": {"Keyword": "BMatrix", "VR": ["FD"], "VM": "6"},
+			"(0019,xx28)": {"Keyword": "BandwidthPerPixelPhaseEncode", "VR": ["FD"], "VM": "1"},
+			"(0019,xx29)": {"Keyword": "MosaicRefAcqTimes", "VR": ["FD"], "VM": "1-n"}
+		}
+	},
+	{
+		"Creator": "GEMS_ACQU_01",
+		"TagDefs": {
+			"(0019,xxbb)": {"Keyword": "UserData20", "VR": ["DS"], "VM": "1"},
+			"(0019,xxbc)": {"Keyword": "UserData21", "VR": ["DS"], "VM": "1"},
+			"(0019,xxbd)": {"Keyword": "UserData22", "VR": ["DS"], "VM": "1"}
+		}
+	},
+	{
+		"Creator": "GEMS_SERS_01",
+		"TagDefs": {
+			"(0025,xx07)": {"Keyword": "ImagesInSeries", "VR": ["SL"], "VM": "1"}
+		}
+	},
+	{
+		"Creator": "GEMS_PARM_01",
+		"TagDefs": {
+			"(0043,xx39)": {"Keyword": "SlopInt6To9", "VR": ["IS"], "VM": "4"}
+		}
+	},
+	{
+		"Creator": "Philips Imaging DD 001",
+		"TagDefs": {
+			"(2001,xx03)": {"Keyword": "DiffusionBFactor", "VR": ["FL"], "VM": "1"},
+			"(2001,xx04)": {"Keyword": "DiffusionDirection", "VR": ["CS"], "VM": "1"}
+		}
+	},
+	{
+		"Creator": "Philips MR Imaging DD 001",
+		"TagDefs": {
+			"(2005,xx0d)": {"Keyword": "ScaleIntercept", "VR": ["FL"], "VM": "1"},
+			"(2005,xx0e)": {"Keyword": "ScaleSlope", "VR": ["FL"], "VM": "1"}
+		}
+	}
+]
+`
@@ -0,0 +1,41 @@
+package dicomprivate
+
+import (
+	"encoding/json"
+	"testing"
+
+	gd "github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+	"github.com/macadamian/dicom"
+)
+
+func TestRegistered(t *testing.T) {
+	dicts := []dicom.PrivateDictionary{}
+	if err := json.Unmarshal([]byte(DictionariesStr), &dicts); err != nil {
+		t.Fatalf("Unmarshal DictionariesStr error: %v", err)
+	}
+
+	registered := map[string]bool{}
+	for _, c := range dicom.PrivateCreators() {
+		registered[c] = true
+	}
+	for _, d := range dicts {
+		if !registered[d.Creator] {
+			t.Errorf("PrivateCreators() = %v, want %q", dicom.PrivateCreators(), d.Creator)
+		}
+		if len(d.TagDefs) == 0 {
+			t.Errorf("%s has no tag definitions", d.Creator)
+		}
+	}
+}
+
+func TestLookupPrivateTag(t *testing.T) {
+	ds := &gd.DataSet{Elements: []*gd.Element{
+		{Tag: dicomtag.Tag{Group: 0x0019, Element: 0x0010}, VR: "LO", Value: []interface{}{"SIEMENS MR HEADER"}},
+	}}
+
+	creator, td, ok := dicom.LookupPrivateTag(ds, dicomtag.Tag{Group: 0x0019, Element: 0x100c})
+	if !ok || creator != "SIEMENS MR HEADER" || td.Keyword != "BValue" || len(td.VR) != 1 || td.VR[0] != "IS" {
+		t.Errorf("LookupPrivateTag((0019,100c)) = %q, %+v, %v, want the BValue of SIEMENS MR HEADER", creator, td, ok)
+	}
+}
@@ -0,0 +1,80 @@
+package dicomvalue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AgeUnit is the unit of an age.
+type AgeUnit byte
+
+const (
+	Days   AgeUnit = 'D'
+	Weeks  AgeUnit = 'W'
+	Months AgeUnit = 'M'
+	Years  AgeUnit = 'Y'
+)
+
+// An Age is an AS value (e.g. "018Y" for 18 years). The zero Age is an empty value.
+type Age struct {
+	Value int
+	Unit  AgeUnit
+}
+
+// ParseAge parses an AS value, which is three digits followed by the unit.
+func ParseAge(s string) (Age, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Age{}, nil
+	}
+
+	if len(s) != 4 {
+		return Age{}, fmt.Errorf("Invalid age %q, expected nnnD, nnnW, nnnM or nnnY", s)
+	}
+
+	n, err := strconv.Atoi(s[:3])
+	if err != nil || n < 0 || strings.IndexAny(s[:3], "+-") >= 0 {
+		return Age{}, fmt.Errorf("Invalid age %q, expected nnnD, nnnW, nnnM or nnnY", s)
+	}
+
+	unit := AgeUnit(s[3])
+	switch unit {
+	case Days, Weeks, Months, Years:
+	default:
+		return Age{}, fmt.Errorf("Invalid age %q, the unit must be D, W, M or Y", s)
+	}
+
+	return Age{Value: n, Unit: unit}, nil
+}
+
+// IsZero reports whether the age is empty.
+func (a Age) IsZero() bool {
+	return a.Unit == 0
+}
+
+// String provides the age in the AS form.
+func (a Age) String() string {
+	if a.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%03d%c", a.Value, a.Unit)
+}
+
+// MarshalText encodes the age in the AS form. The value must fit in three digits.
+func (a Age) MarshalText() ([]byte, error) {
+	if !a.IsZero() && (a.Value < 0 || a.Value > 999) {
+		return nil, fmt.Errorf("Age %d%c does not fit in an AS value", a.Value, a.Unit)
+	}
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes an AS value.
+func (a *Age) UnmarshalText(b []byte) error {
+	v, err := ParseAge(string(b))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
@@ -0,0 +1,58 @@
+package dicomvalue
+
+import "testing"
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Age
+		str  string
+	}{
+		{"018Y", Age{Value: 18, Unit: Years}, "018Y"},
+		{"003W", Age{Value: 3, Unit: Weeks}, "003W"},
+		{"011M", Age{Value: 11, Unit: Months}, "011M"},
+		{"120D", Age{Value: 120, Unit: Days}, "120D"},
+		{"000D", Age{Value: 0, Unit: Days}, "000D"},
+		{" 045Y ", Age{Value: 45, Unit: Years}, "045Y"},
+		{"", Age{}, ""},
+	}
+
+	for _, tt := range tests {
+		a, err := ParseAge(tt.in)
+		if err != nil {
+			t.Errorf("ParseAge(%q) error: %v", tt.in, err)
+			continue
+		}
+		if a != tt.want || a.String() != tt.str {
+			t.Errorf("ParseAge(%q) = %+v (%q), want %+v (%q)", tt.in, a, a, tt.want, tt.str)
+		}
+	}
+}
+
+func TestParseAgeInvalid(t *testing.T) {
+	for _, in := range []string{"18Y", "0018Y", "018", "018y", "018X", "-18Y", "+18Y", "01 Y", "abcY"} {
+		if a, err := ParseAge(in); err == nil {
+			t.Errorf("ParseAge(%q) = %+v, want an error", in, a)
+		}
+	}
+}
+
+func TestAgeText(t *testing.T) {
+	var a Age
+	if err := a.UnmarshalText([]byte("065Y")); err != nil || a != (Age{Value: 65, Unit: Years}) {
+		t.Errorf("UnmarshalText(065Y) = %+v, %v", a, err)
+	}
+	if b, err := a.MarshalText(); err != nil || string(b) != "065Y" {
+		t.Errorf("MarshalText() = %q, %v, want 065Y", b, err)
+	}
+
+	// The zero age is empty and the ages that don't fit in three digits are refused
+	if b, err := (Age{}).MarshalText(); err != nil || string(b) != "" {
+		t.Errorf("Age{}.MarshalText() = %q, %v, want an empty value", b, err)
+	}
+	for _, a := range []Age{{Value: 1000, Unit: Days}, {Value: -1, Unit: Years}} {
+		if b, err := a.MarshalText(); err == nil {
+			t.Errorf("%+v.MarshalText() = %q, want an error", a, b)
+		}
+	}
+}
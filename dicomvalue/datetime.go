@@ -0,0 +1,444 @@
+// Package dicomvalue provides Go representations of the DICOM value representations that are
+// encoded as strings, but hold structured values: dates and times (DA, TM, DT), person names
+// (PN), numbers (DS, IS) and ages (AS). The types implement encoding.TextMarshaler and
+// encoding.TextUnmarshaler with the encoding from PS3.5 so that they can be used as the fields
+// of the generated storage classes and converted by Marshal and Unmarshal.
+package dicomvalue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Precision is the smallest component present in a time (TM) or date time (DT) value since
+// the trailing components may be left out (e.g. "1530" for 15:30 in a TM value).
+type Precision int
+
+const (
+	PrecisionYear Precision = iota + 1
+	PrecisionMonth
+	PrecisionDay
+	PrecisionHour
+	PrecisionMinute
+	PrecisionSecond
+	// PrecisionFraction is a value with a fraction of a second (up to a microsecond).
+	PrecisionFraction
+)
+
+// A Date is a DA value (e.g. "19850307"). It is a calendar date without a time of day. The
+// zero Date is an empty value.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+	// Location is the offset from UTC that applies to the date, if one is known. Dates don't
+	// have an offset of their own, but it may be provided by the Timezone Offset From UTC
+	// (0008,0201) attribute of the dataset.
+	Location *time.Location
+}
+
+// ParseDate parses a DA value. The "YYYY.MM.DD" form from ACR-NEMA is also accepted.
+func ParseDate(s string) (Date, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Date{}, nil
+	}
+
+	digits := s
+	if len(s) == 10 && s[4] == '.' && s[7] == '.' {
+		digits = s[0:4] + s[5:7] + s[8:10]
+	}
+	if len(digits) != 8 {
+		return Date{}, fmt.Errorf("Invalid date %q, expected YYYYMMDD", s)
+	}
+
+	n, err := parseDigits(digits, 4, 2, 2)
+	if err != nil {
+		return Date{}, fmt.Errorf("Invalid date %q: %v", s, err)
+	}
+
+	d := Date{Year: n[0], Month: time.Month(n[1]), Day: n[2]}
+	if t := d.Time(); t.Year() != d.Year || t.Month() != d.Month || t.Day() != d.Day {
+		return Date{}, fmt.Errorf("Invalid date %q, it is not a day of the calendar", s)
+	}
+
+	return d, nil
+}
+
+// IsZero reports whether the date is empty.
+func (d Date) IsZero() bool {
+	return d.Year == 0 && d.Month == 0 && d.Day == 0
+}
+
+// Time provides the start of the day in the Location of the date, or UTC if there is none.
+func (d Date) Time() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, locationOrUTC(d.Location))
+}
+
+// WithDefaultLocation provides the date in the location if it doesn't already have one.
+func (d Date) WithDefaultLocation(loc *time.Location) Date {
+	if d.Location == nil {
+		d.Location = loc
+	}
+	return d
+}
+
+// String provides the date in the DA form.
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%04d%02d%02d", d.Year, d.Month, d.Day)
+}
+
+// MarshalText encodes the date in the DA form.
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a DA value.
+func (d *Date) UnmarshalText(b []byte) error {
+	v, err := ParseDate(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// A Time is a TM value (e.g. "153000.25"). It is a time of day that may be missing its minutes,
+// seconds and fraction as indicated by the Precision. The zero Time is an empty value.
+type Time struct {
+	Hour       int
+	Minute     int
+	Second     int
+	Nanosecond int
+	Precision  Precision
+	// Location is the offset from UTC that applies to the time, if one is known. Like dates,
+	// times don't have an offset of their own.
+	Location *time.Location
+}
+
+// ParseTime parses a TM value. The "HH:MM:SS.FFFFFF" form from ACR-NEMA is also accepted.
+func ParseTime(s string) (Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Time{}, nil
+	}
+
+	var t Time
+	prec, err := parseClock(strings.Replace(s, ":", "", -1), &t.Hour, &t.Minute, &t.Second, &t.Nanosecond)
+	if err != nil {
+		return Time{}, fmt.Errorf("Invalid time %q: %v", s, err)
+	}
+	t.Precision = prec
+
+	return t, nil
+}
+
+// IsZero reports whether the time is empty.
+func (t Time) IsZero() bool {
+	return t.Precision == 0
+}
+
+// On provides the time on a date in the Location of the time, the date or UTC, in that order.
+func (t Time) On(d Date) time.Time {
+	loc := t.Location
+	if loc == nil {
+		loc = d.Location
+	}
+	return time.Date(d.Year, d.Month, d.Day, t.Hour, t.Minute, t.Second, t.Nanosecond, locationOrUTC(loc))
+}
+
+// WithDefaultLocation provides the time in the location if it doesn't already have one.
+func (t Time) WithDefaultLocation(loc *time.Location) Time {
+	if t.Location == nil {
+		t.Location = loc
+	}
+	return t
+}
+
+// String provides the time in the TM form with the components up to its precision.
+func (t Time) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return formatClock(t.Precision, t.Hour, t.Minute, t.Second, t.Nanosecond)
+}
+
+// MarshalText encodes the time in the TM form.
+func (t Time) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes a TM value.
+func (t *Time) UnmarshalText(b []byte) error {
+	v, err := ParseTime(string(b))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// A DateTime is a DT value (e.g. "20190307153000.25-0500"). The components after the year may
+// be left out as indicated by the Precision and they are at their minimum in the Time (e.g.
+// January for a year precision). The zero DateTime is an empty value.
+type DateTime struct {
+	// Time is in the offset from UTC of the value when it has one. Otherwise it is in the
+	// default location that was applied, or UTC.
+	Time      time.Time
+	Precision Precision
+	// HasOffset reports whether the value included its own offset from UTC (e.g. "-0500").
+	HasOffset bool
+}
+
+// ParseDateTime parses a DT value.
+func ParseDateTime(s string) (DateTime, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DateTime{}, nil
+	}
+
+	dt := DateTime{}
+	loc := time.UTC
+	value := s
+	if i := strings.IndexAny(s, "+-"); i >= 0 {
+		var err error
+		loc, err = ParseOffset(s[i:])
+		if err != nil {
+			return DateTime{}, fmt.Errorf("Invalid date time %q: %v", s, err)
+		}
+		value = s[:i]
+		dt.HasOffset = true
+	}
+
+	if len(value) < 4 {
+		return DateTime{}, fmt.Errorf("Invalid date time %q, expected at least a year", s)
+	}
+
+	year, month, day := 0, 1, 1
+	var hour, minute, second, nanosecond int
+
+	datelen := len(value)
+	if datelen > 8 {
+		datelen = 8
+	}
+	if datelen%2 == 1 {
+		return DateTime{}, fmt.Errorf("Invalid date time %q, a component is incomplete", s)
+	}
+	n, err := parseDigits(value[:datelen], 4, 2, 2)
+	if err != nil {
+		return DateTime{}, fmt.Errorf("Invalid date time %q: %v", s, err)
+	}
+	dt.Precision = PrecisionYear + Precision(len(n)-1)
+	year = n[0]
+	if len(n) > 1 {
+		month = n[1]
+	}
+	if len(n) > 2 {
+		day = n[2]
+	}
+
+	if len(value) > 8 {
+		dt.Precision, err = parseClock(value[8:], &hour, &minute, &second, &nanosecond)
+		if err != nil {
+			return DateTime{}, fmt.Errorf("Invalid date time %q: %v", s, err)
+		}
+	}
+
+	dt.Time = time.Date(year, time.Month(month), day, hour, minute, second, nanosecond, loc)
+	if dt.Time.Month() != time.Month(month) || dt.Time.Day() != day {
+		return DateTime{}, fmt.Errorf("Invalid date time %q, it is not a day of the calendar", s)
+	}
+
+	return dt, nil
+}
+
+// IsZero reports whether the date time is empty.
+func (dt DateTime) IsZero() bool {
+	return dt.Precision == 0
+}
+
+// WithDefaultLocation provides the date time with the same clock reading in the location when
+// the value doesn't have an offset of its own.
+func (dt DateTime) WithDefaultLocation(loc *time.Location) DateTime {
+	if dt.HasOffset || loc == nil || dt.IsZero() {
+		return dt
+	}
+	t := dt.Time
+	dt.Time = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	return dt
+}
+
+// String provides the date time in the DT form with the components up to its precision. The
+// offset is only included if the value had one of its own.
+func (dt DateTime) String() string {
+	if dt.IsZero() {
+		return ""
+	}
+
+	t := dt.Time
+	s := fmt.Sprintf("%04d", t.Year())
+	if dt.Precision >= PrecisionMonth {
+		s += fmt.Sprintf("%02d", t.Month())
+	}
+	if dt.Precision >= PrecisionDay {
+		s += fmt.Sprintf("%02d", t.Day())
+	}
+	if dt.Precision >= PrecisionHour {
+		s += formatClock(dt.Precision, t.Hour(), t.Minute(), t.Second(), t.Nanosecond())
+	}
+	if dt.HasOffset {
+		s += FormatOffset(t.Location(), t)
+	}
+
+	return s
+}
+
+// MarshalText encodes the date time in the DT form.
+func (dt DateTime) MarshalText() ([]byte, error) {
+	return []byte(dt.String()), nil
+}
+
+// UnmarshalText decodes a DT value.
+func (dt *DateTime) UnmarshalText(b []byte) error {
+	v, err := ParseDateTime(string(b))
+	if err != nil {
+		return err
+	}
+	*dt = v
+	return nil
+}
+
+// ParseOffset parses an offset from UTC in the "&ZZXX" form used by DT values and the
+// Timezone Offset From UTC (0008,0201) attribute (e.g. "-0500" or "+0530").
+func ParseOffset(s string) (*time.Location, error) {
+	s = strings.TrimSpace(s)
+	if len(s) != 5 || (s[0] != '+' && s[0] != '-') {
+		return nil, fmt.Errorf("Invalid offset from UTC %q, expected &ZZXX", s)
+	}
+
+	n, err := parseDigits(s[1:], 2, 2)
+	if err != nil || len(n) != 2 || n[0] > 14 || n[1] > 59 {
+		return nil, fmt.Errorf("Invalid offset from UTC %q, expected &ZZXX", s)
+	}
+
+	secs := n[0]*3600 + n[1]*60
+	if s[0] == '-' {
+		secs = -secs
+	}
+
+	return time.FixedZone(s, secs), nil
+}
+
+// FormatOffset provides the offset from UTC of a location at an instant in the "&ZZXX" form.
+func FormatOffset(loc *time.Location, at time.Time) string {
+	_, secs := at.In(locationOrUTC(loc)).Zone()
+
+	sign := '+'
+	if secs < 0 {
+		sign = '-'
+		secs = -secs
+	}
+
+	return fmt.Sprintf("%c%02d%02d", sign, secs/3600, secs/60%60)
+}
+
+func locationOrUTC(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Parse a string of digits into numbers with the provided widths. The string may end after
+// any of the numbers, but not in the middle of one.
+func parseDigits(s string, widths ...int) ([]int, error) {
+	n := []int{}
+	for _, w := range widths {
+		if s == "" {
+			break
+		}
+		if len(s) < w {
+			return nil, fmt.Errorf("incomplete component %q", s)
+		}
+		v, err := strconv.Atoi(s[:w])
+		if err != nil || strings.IndexAny(s[:w], "+-") >= 0 {
+			return nil, fmt.Errorf("bad component %q", s[:w])
+		}
+		n = append(n, v)
+		s = s[w:]
+	}
+	if s != "" {
+		return nil, fmt.Errorf("unexpected %q", s)
+	}
+	return n, nil
+}
+
+// Parse the "HHMMSS.FFFFFF" clock of TM and DT values, where everything after the hour is optional.
+func parseClock(s string, hour, minute, second, nanosecond *int) (Precision, error) {
+	frac := ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s, frac = s[:i], s[i+1:]
+	}
+
+	n, err := parseDigits(s, 2, 2, 2)
+	if err != nil {
+		return 0, err
+	}
+	if len(n) == 0 {
+		return 0, fmt.Errorf("missing hour")
+	}
+
+	prec := PrecisionHour + Precision(len(n)-1)
+	*hour = n[0]
+	if len(n) > 1 {
+		*minute = n[1]
+	}
+	if len(n) > 2 {
+		*second = n[2]
+	}
+	if *hour > 23 || *minute > 59 || *second > 60 {
+		return 0, fmt.Errorf("component out of range")
+	}
+
+	if frac != "" {
+		if len(n) != 3 || len(frac) > 6 {
+			return 0, fmt.Errorf("bad fraction %q", frac)
+		}
+		f, err := strconv.Atoi(frac)
+		if err != nil || f < 0 {
+			return 0, fmt.Errorf("bad fraction %q", frac)
+		}
+		for i := len(frac); i < 9; i++ {
+			f *= 10
+		}
+		*nanosecond = f
+		prec = PrecisionFraction
+	}
+
+	return prec, nil
+}
+
+// Format the clock of TM and DT values with the components up to the precision. Fractions are
+// given to the microsecond without trailing zeros.
+func formatClock(prec Precision, hour, minute, second, nanosecond int) string {
+	s := fmt.Sprintf("%02d", hour)
+	if prec >= PrecisionMinute {
+		s += fmt.Sprintf("%02d", minute)
+	}
+	if prec >= PrecisionSecond {
+		s += fmt.Sprintf("%02d", second)
+	}
+	if prec >= PrecisionFraction {
+		frac := strings.TrimRight(fmt.Sprintf("%06d", nanosecond/1000), "0")
+		if frac == "" {
+			frac = "0"
+		}
+		s += "." + frac
+	}
+	return s
+}
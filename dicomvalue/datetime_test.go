@@ -0,0 +1,82 @@
+package dicomvalue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"19850307", "19850307", true},
+		{"1985.03.07", "19850307", true},
+		{"", "", true},
+		{"19850230", "", false},
+		{"198503", "", false},
+		{"1985O307", "", false},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseDate(tt.in)
+		if (err == nil) != tt.ok || d.String() != tt.want {
+			t.Errorf("ParseDate(%q) = %q, %v, want %q (ok %v)", tt.in, d, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		prec Precision
+		ok   bool
+	}{
+		{"153000", "153000", PrecisionSecond, true},
+		{"1530", "1530", PrecisionMinute, true},
+		{"15", "15", PrecisionHour, true},
+		{"15:30:00", "153000", PrecisionSecond, true},
+		{"153000.25", "153000.25", PrecisionFraction, true},
+		{"", "", 0, true},
+		{"2500", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		tm, err := ParseTime(tt.in)
+		if (err == nil) != tt.ok || tm.String() != tt.want || tm.Precision != tt.prec {
+			t.Errorf("ParseTime(%q) = %q (precision %d), %v, want %q (precision %d, ok %v)", tt.in, tm, tm.Precision, err, tt.want, tt.prec, tt.ok)
+		}
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		prec   Precision
+		offset bool
+		ok     bool
+	}{
+		{"20190307153000.25-0500", "20190307153000.25-0500", PrecisionFraction, true, true},
+		{"2019", "2019", PrecisionYear, false, true},
+		{"201903", "201903", PrecisionMonth, false, true},
+		{"20190307", "20190307", PrecisionDay, false, true},
+		{"201903071530+0100", "201903071530+0100", PrecisionMinute, true, true},
+		{"201", "", 0, false, false},
+		{"20190230", "", 0, false, false},
+	}
+
+	for _, tt := range tests {
+		dt, err := ParseDateTime(tt.in)
+		if (err == nil) != tt.ok || dt.String() != tt.want || dt.Precision != tt.prec || dt.HasOffset != tt.offset {
+			t.Errorf("ParseDateTime(%q) = %q (precision %d, offset %v), %v, want %q", tt.in, dt, dt.Precision, dt.HasOffset, err, tt.want)
+		}
+	}
+
+	dt, _ := ParseDateTime("20190307153000")
+	if got := dt.WithDefaultLocation(time.FixedZone("", -5*3600)).Time.UTC().Hour(); got != 20 {
+		t.Errorf("WithDefaultLocation hour in UTC = %d, want 20", got)
+	}
+}
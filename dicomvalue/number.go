@@ -0,0 +1,63 @@
+package dicomvalue
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrEmpty is the error of ParseDS and ParseIS for an empty value, such as the value of a Type 2
+// attribute that is not known, which must be kept apart from a zero.
+var ErrEmpty = errors.New("Empty number")
+
+// ParseDS parses a DS (decimal string) value, which may have leading and trailing spaces. It
+// provides ErrEmpty if the value is empty.
+func ParseDS(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrEmpty
+	}
+
+	// Only the fixed and exponential forms are allowed, unlike ParseFloat which also accepts
+	// "Inf", "NaN" and hexadecimal forms
+	if strings.Trim(s, "0123456789+-.eE") != "" {
+		return 0, fmt.Errorf("Invalid decimal string %q", s)
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid decimal string %q", s)
+	}
+	return f, nil
+}
+
+// FormatDS provides the DS form of a number with as much precision as fits in the 16
+// characters allowed for the value.
+func FormatDS(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	for prec := 16; len(s) > 16 && prec > 0; prec-- {
+		s = strconv.FormatFloat(f, 'g', prec, 64)
+	}
+	return s
+}
+
+// ParseIS parses an IS (integer string) value, which may have leading and trailing spaces. It
+// provides ErrEmpty if the value is empty.
+func ParseIS(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrEmpty
+	}
+
+	n, err := strconv.ParseInt(strings.TrimPrefix(s, "+"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid integer string %q", s)
+	}
+	return n, nil
+}
+
+// FormatIS provides the IS form of an integer.
+func FormatIS(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
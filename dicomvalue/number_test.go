@@ -0,0 +1,77 @@
+package dicomvalue
+
+import "testing"
+
+func TestParseDS(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  error
+	}{
+		{"1.5", 1.5, nil},
+		{" -2.5e3 ", -2500, nil},
+		{"+0", 0, nil},
+		{"0", 0, nil},
+		{"", 0, ErrEmpty},
+		{"   ", 0, ErrEmpty},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseDS(tt.in)
+		if f != tt.want || err != tt.err {
+			t.Errorf("ParseDS(%q) = %v, %v, want %v, %v", tt.in, f, err, tt.want, tt.err)
+		}
+	}
+
+	for _, in := range []string{"NaN", "Inf", "0x10", "1,5", "abc"} {
+		if f, err := ParseDS(in); err == nil || err == ErrEmpty {
+			t.Errorf("ParseDS(%q) = %v, %v, want a parse error", in, f, err)
+		}
+	}
+}
+
+func TestParseIS(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		err  error
+	}{
+		{"42", 42, nil},
+		{" +7 ", 7, nil},
+		{"-12", -12, nil},
+		{"0", 0, nil},
+		{"", 0, ErrEmpty},
+	}
+
+	for _, tt := range tests {
+		n, err := ParseIS(tt.in)
+		if n != tt.want || err != tt.err {
+			t.Errorf("ParseIS(%q) = %v, %v, want %v, %v", tt.in, n, err, tt.want, tt.err)
+		}
+	}
+
+	for _, in := range []string{"1.5", "1e3", "x"} {
+		if n, err := ParseIS(in); err == nil || err == ErrEmpty {
+			t.Errorf("ParseIS(%q) = %v, %v, want a parse error", in, n, err)
+		}
+	}
+}
+
+func TestFormatDS(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{1.5, "1.5"},
+		{0, "0"},
+		{-2500, "-2500"},
+		{1.0 / 3, "0.33333333333333"},
+	}
+
+	for _, tt := range tests {
+		s := FormatDS(tt.in)
+		if s != tt.want || len(s) > 16 {
+			t.Errorf("FormatDS(%v) = %q, want %q", tt.in, s, tt.want)
+		}
+	}
+}
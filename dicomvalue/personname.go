@@ -0,0 +1,107 @@
+package dicomvalue
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A PersonName is a PN value (e.g. "Doe^John" or "Yamada^Tarou=山田^太郎=やまだ^たろう"). It has up
+// to three component groups for the different representations of the name. The zero PersonName
+// is an empty value.
+type PersonName struct {
+	Alphabetic  PersonNameGroup
+	Ideographic PersonNameGroup
+	Phonetic    PersonNameGroup
+}
+
+// A PersonNameGroup is one representation of a person name, split into its five components.
+type PersonNameGroup struct {
+	FamilyName string
+	GivenName  string
+	MiddleName string
+	NamePrefix string
+	NameSuffix string
+}
+
+// ParsePersonName parses a PN value with its component groups separated by "=" and the
+// components of each group separated by "^".
+func ParsePersonName(s string) (PersonName, error) {
+	s = strings.TrimRight(s, " ")
+
+	groups := strings.Split(s, "=")
+	if len(groups) > 3 {
+		return PersonName{}, fmt.Errorf("Invalid person name %q, it has %d component groups", s, len(groups))
+	}
+
+	pn := PersonName{}
+	for i, g := range groups {
+		components := strings.Split(g, "^")
+		if len(components) > 5 {
+			return PersonName{}, fmt.Errorf("Invalid person name %q, it has %d components in a group", s, len(components))
+		}
+		for len(components) < 5 {
+			components = append(components, "")
+		}
+
+		png := PersonNameGroup{
+			FamilyName: components[0],
+			GivenName:  components[1],
+			MiddleName: components[2],
+			NamePrefix: components[3],
+			NameSuffix: components[4],
+		}
+		switch i {
+		case 0:
+			pn.Alphabetic = png
+		case 1:
+			pn.Ideographic = png
+		case 2:
+			pn.Phonetic = png
+		}
+	}
+
+	return pn, nil
+}
+
+// IsZero reports whether the name is empty.
+func (pn PersonName) IsZero() bool {
+	return pn.Alphabetic.IsZero() && pn.Ideographic.IsZero() && pn.Phonetic.IsZero()
+}
+
+// String provides the name in the PN form without trailing empty groups or components.
+func (pn PersonName) String() string {
+	groups := []string{pn.Alphabetic.String(), pn.Ideographic.String(), pn.Phonetic.String()}
+	for len(groups) > 0 && groups[len(groups)-1] == "" {
+		groups = groups[:len(groups)-1]
+	}
+	return strings.Join(groups, "=")
+}
+
+// MarshalText encodes the name in the PN form.
+func (pn PersonName) MarshalText() ([]byte, error) {
+	return []byte(pn.String()), nil
+}
+
+// UnmarshalText decodes a PN value.
+func (pn *PersonName) UnmarshalText(b []byte) error {
+	v, err := ParsePersonName(string(b))
+	if err != nil {
+		return err
+	}
+	*pn = v
+	return nil
+}
+
+// IsZero reports whether all of the components of the group are empty.
+func (png PersonNameGroup) IsZero() bool {
+	return png == PersonNameGroup{}
+}
+
+// String provides the group with its components separated by "^" and without trailing empty components.
+func (png PersonNameGroup) String() string {
+	components := []string{png.FamilyName, png.GivenName, png.MiddleName, png.NamePrefix, png.NameSuffix}
+	for len(components) > 0 && components[len(components)-1] == "" {
+		components = components[:len(components)-1]
+	}
+	return strings.Join(components, "^")
+}
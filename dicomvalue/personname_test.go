@@ -0,0 +1,64 @@
+package dicomvalue
+
+import "testing"
+
+func TestParsePersonName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want PersonName
+		str  string
+	}{
+		{"Doe^John", PersonName{Alphabetic: PersonNameGroup{FamilyName: "Doe", GivenName: "John"}}, "Doe^John"},
+		{"Doe^John^Q^Dr^Jr", PersonName{Alphabetic: PersonNameGroup{"Doe", "John", "Q", "Dr", "Jr"}}, "Doe^John^Q^Dr^Jr"},
+		{"Yamada^Tarou=山田^太郎=やまだ^たろう", PersonName{
+			Alphabetic:  PersonNameGroup{FamilyName: "Yamada", GivenName: "Tarou"},
+			Ideographic: PersonNameGroup{FamilyName: "山田", GivenName: "太郎"},
+			Phonetic:    PersonNameGroup{FamilyName: "やまだ", GivenName: "たろう"},
+		}, "Yamada^Tarou=山田^太郎=やまだ^たろう"},
+		// Only an ideographic representation
+		{"=山田^太郎", PersonName{Ideographic: PersonNameGroup{FamilyName: "山田", GivenName: "太郎"}}, "=山田^太郎"},
+		// The trailing padding, empty components and empty groups are dropped
+		{"Doe^John^^^ ", PersonName{Alphabetic: PersonNameGroup{FamilyName: "Doe", GivenName: "John"}}, "Doe^John"},
+		{"Doe^John==", PersonName{Alphabetic: PersonNameGroup{FamilyName: "Doe", GivenName: "John"}}, "Doe^John"},
+		{"", PersonName{}, ""},
+	}
+
+	for _, tt := range tests {
+		pn, err := ParsePersonName(tt.in)
+		if err != nil {
+			t.Errorf("ParsePersonName(%q) error: %v", tt.in, err)
+			continue
+		}
+		if pn != tt.want {
+			t.Errorf("ParsePersonName(%q) = %+v, want %+v", tt.in, pn, tt.want)
+		}
+		if pn.String() != tt.str {
+			t.Errorf("ParsePersonName(%q).String() = %q, want %q", tt.in, pn.String(), tt.str)
+		}
+		if pn.IsZero() != (tt.str == "") {
+			t.Errorf("ParsePersonName(%q).IsZero() = %v", tt.in, pn.IsZero())
+		}
+	}
+}
+
+func TestParsePersonNameInvalid(t *testing.T) {
+	for _, in := range []string{"a=b=c=d", "a^b^c^d^e^f"} {
+		if pn, err := ParsePersonName(in); err == nil {
+			t.Errorf("ParsePersonName(%q) = %+v, want an error", in, pn)
+		}
+	}
+}
+
+func TestPersonNameText(t *testing.T) {
+	var pn PersonName
+	if err := pn.UnmarshalText([]byte("Doe^Jane=ドウ^ジェーン")); err != nil {
+		t.Fatalf("UnmarshalText error: %v", err)
+	}
+	b, err := pn.MarshalText()
+	if err != nil || string(b) != "Doe^Jane=ドウ^ジェーン" {
+		t.Errorf("MarshalText() = %q, %v, want %q", b, err, "Doe^Jane=ドウ^ジェーン")
+	}
+	if err := pn.UnmarshalText([]byte("a=b=c=d")); err == nil {
+		t.Errorf("UnmarshalText(%q) succeeded, want an error", "a=b=c=d")
+	}
+}
@@ -0,0 +1,256 @@
+package dicom
+
+import (
+	"sort"
+	"strings"
+)
+
+// A SchemaDiff describes the differences between two versions of the schema, from A to B.
+type SchemaDiff struct {
+	AddedClasses   []ClassDef
+	RemovedClasses []ClassDef
+	// ChangedClasses are the SOP Classes in both versions with differences in their module usages.
+	ChangedClasses []ClassDiff
+	// ChangedModules are the module definitions with added, removed or retyped tag usages. Modules
+	// that only exist in one of the versions have all of their tag usages added or removed.
+	ChangedModules []ModuleDiff
+	AddedTags      []TagDefChange
+	RemovedTags    []TagDefChange
+	// ChangedTags are the tag definitions in both versions with a different keyword, VR, VM or
+	// deidentification action.
+	ChangedTags []TagDefChange
+}
+
+// Empty reports whether there are no differences.
+func (d SchemaDiff) Empty() bool {
+	return len(d.AddedClasses) == 0 && len(d.RemovedClasses) == 0 && len(d.ChangedClasses) == 0 &&
+		len(d.ChangedModules) == 0 && len(d.AddedTags) == 0 && len(d.RemovedTags) == 0 && len(d.ChangedTags) == 0
+}
+
+// A ClassDiff describes the differences in the modules of a SOP Class.
+type ClassDiff struct {
+	SOPClassUid    string
+	Name           string
+	AddedModules   []ModuleUsage
+	RemovedModules []ModuleUsage
+	// ChangedModules are modules with a different usage (e.g. "U" to "M").
+	ChangedModules []ModuleUsageChange
+}
+
+// A ModuleUsageChange is a change in the usage of a module within a SOP Class.
+type ModuleUsageChange struct {
+	Name string
+	From string
+	To   string
+}
+
+// A ModuleDiff describes the differences in the tag usages of a module definition.
+type ModuleDiff struct {
+	Name        string
+	AddedTags   []TagUsage
+	RemovedTags []TagUsage
+	RetypedTags []TagUsageChange
+}
+
+// A TagUsageChange is a change in the type of a tag usage within a module.
+type TagUsageChange struct {
+	Path []string
+	From string
+	To   string
+}
+
+// A TagDefChange describes a tag definition that was added, removed or changed. From is the zero
+// TagDef for added tags and To is the zero TagDef for removed tags.
+type TagDefChange struct {
+	Tag  string
+	From TagDef
+	To   TagDef
+	// Fields are the names of the fields that are different (e.g. "VR", "VM") for changed tags.
+	Fields []string
+}
+
+// Diff compares two versions of the schema and describes what changed going from a to b. The
+// results are sorted by SOP Class UID, module name and tag so that they are stable.
+func Diff(a, b *SchemaDef) SchemaDiff {
+	d := SchemaDiff{}
+
+	aclasses := map[string]ClassDef{}
+	for _, cd := range a.ClassDefs {
+		aclasses[cd.SOPClassUid] = cd
+	}
+	bclasses := map[string]ClassDef{}
+	for _, cd := range b.ClassDefs {
+		bclasses[cd.SOPClassUid] = cd
+	}
+
+	for uid, acd := range aclasses {
+		bcd, ok := bclasses[uid]
+		if !ok {
+			d.RemovedClasses = append(d.RemovedClasses, acd)
+			continue
+		}
+		if cdiff, changed := diffClass(acd, bcd); changed {
+			d.ChangedClasses = append(d.ChangedClasses, cdiff)
+		}
+	}
+	for uid, bcd := range bclasses {
+		if _, ok := aclasses[uid]; !ok {
+			d.AddedClasses = append(d.AddedClasses, bcd)
+		}
+	}
+
+	for _, name := range unionKeys(moduleNames(a.ModuleDefs), moduleNames(b.ModuleDefs)) {
+		if mdiff, changed := diffModule(name, a.ModuleDefs[name], b.ModuleDefs[name]); changed {
+			d.ChangedModules = append(d.ChangedModules, mdiff)
+		}
+	}
+
+	for tag, atd := range a.TagDefs {
+		btd, ok := b.TagDefs[tag]
+		if !ok {
+			d.RemovedTags = append(d.RemovedTags, TagDefChange{Tag: tag, From: atd})
+			continue
+		}
+		if fields := diffTagDef(atd, btd); len(fields) > 0 {
+			d.ChangedTags = append(d.ChangedTags, TagDefChange{Tag: tag, From: atd, To: btd, Fields: fields})
+		}
+	}
+	for tag, btd := range b.TagDefs {
+		if _, ok := a.TagDefs[tag]; !ok {
+			d.AddedTags = append(d.AddedTags, TagDefChange{Tag: tag, To: btd})
+		}
+	}
+
+	sortClasses := func(cds []ClassDef) {
+		sort.Slice(cds, func(i, j int) bool { return cds[i].SOPClassUid < cds[j].SOPClassUid })
+	}
+	sortTags := func(tds []TagDefChange) {
+		sort.Slice(tds, func(i, j int) bool { return tds[i].Tag < tds[j].Tag })
+	}
+	sortClasses(d.AddedClasses)
+	sortClasses(d.RemovedClasses)
+	sort.Slice(d.ChangedClasses, func(i, j int) bool { return d.ChangedClasses[i].SOPClassUid < d.ChangedClasses[j].SOPClassUid })
+	sortTags(d.AddedTags)
+	sortTags(d.RemovedTags)
+	sortTags(d.ChangedTags)
+
+	return d
+}
+
+func diffClass(a, b ClassDef) (ClassDiff, bool) {
+	cdiff := ClassDiff{SOPClassUid: b.SOPClassUid, Name: b.Name}
+
+	ausages := map[string]string{}
+	for _, mu := range a.Modules {
+		ausages[mu.Name] = mu.Usage
+	}
+	busages := map[string]string{}
+	for _, mu := range b.Modules {
+		busages[mu.Name] = mu.Usage
+	}
+
+	for _, mu := range a.Modules {
+		busage, ok := busages[mu.Name]
+		if !ok {
+			cdiff.RemovedModules = append(cdiff.RemovedModules, mu)
+		} else if busage != mu.Usage {
+			cdiff.ChangedModules = append(cdiff.ChangedModules, ModuleUsageChange{Name: mu.Name, From: mu.Usage, To: busage})
+		}
+	}
+	for _, mu := range b.Modules {
+		if _, ok := ausages[mu.Name]; !ok {
+			cdiff.AddedModules = append(cdiff.AddedModules, mu)
+		}
+	}
+
+	changed := len(cdiff.AddedModules) > 0 || len(cdiff.RemovedModules) > 0 || len(cdiff.ChangedModules) > 0
+	return cdiff, changed
+}
+
+func diffModule(name string, a, b ModuleDef) (ModuleDiff, bool) {
+	mdiff := ModuleDiff{Name: name}
+
+	// Tag usages are identified by their path, the first usage wins if the same path is repeated
+	atypes := map[string]string{}
+	for _, tu := range a.Tags {
+		if _, ok := atypes[strings.Join(tu.Path, ">")]; !ok {
+			atypes[strings.Join(tu.Path, ">")] = strings.TrimSpace(tu.Type)
+		}
+	}
+	btypes := map[string]string{}
+	for _, tu := range b.Tags {
+		if _, ok := btypes[strings.Join(tu.Path, ">")]; !ok {
+			btypes[strings.Join(tu.Path, ">")] = strings.TrimSpace(tu.Type)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, tu := range a.Tags {
+		key := strings.Join(tu.Path, ">")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		btype, ok := btypes[key]
+		if !ok {
+			mdiff.RemovedTags = append(mdiff.RemovedTags, tu)
+		} else if btype != atypes[key] {
+			mdiff.RetypedTags = append(mdiff.RetypedTags, TagUsageChange{Path: tu.Path, From: atypes[key], To: btype})
+		}
+	}
+	seen = map[string]bool{}
+	for _, tu := range b.Tags {
+		key := strings.Join(tu.Path, ">")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if _, ok := atypes[key]; !ok {
+			mdiff.AddedTags = append(mdiff.AddedTags, tu)
+		}
+	}
+
+	changed := len(mdiff.AddedTags) > 0 || len(mdiff.RemovedTags) > 0 || len(mdiff.RetypedTags) > 0
+	return mdiff, changed
+}
+
+func diffTagDef(a, b TagDef) []string {
+	fields := []string{}
+	if a.Keyword != b.Keyword {
+		fields = append(fields, "Keyword")
+	}
+	if strings.Join(a.VR, " or ") != strings.Join(b.VR, " or ") {
+		fields = append(fields, "VR")
+	}
+	if a.VM.String() != b.VM.String() {
+		fields = append(fields, "VM")
+	}
+	if a.Deidentify != b.Deidentify {
+		fields = append(fields, "Deidentify")
+	}
+	return fields
+}
+
+func moduleNames(mds map[string]ModuleDef) []string {
+	names := []string{}
+	for name := range mds {
+		names = append(names, name)
+	}
+	return names
+}
+
+// The sorted union of two lists of keys
+func unionKeys(a, b []string) []string {
+	seen := map[string]bool{}
+	keys := []string{}
+	for _, k := range append(append([]string{}, a...), b...) {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
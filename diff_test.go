@@ -0,0 +1,134 @@
+package dicom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testDiffSchemas() (*SchemaDef, *SchemaDef) {
+	a := &SchemaDef{
+		ClassDefs: []ClassDef{
+			{SOPClassUid: "1.2.3.1", Name: "Kept Image Storage", Modules: []ModuleUsage{{Name: "Patient", Usage: "M"}, {Name: "Image", Usage: "U"}, {Name: "Old", Usage: "M"}}},
+			{SOPClassUid: "1.2.3.2", Name: "Removed Image Storage", Modules: []ModuleUsage{{Name: "Patient", Usage: "M"}}},
+		},
+		ModuleDefs: map[string]ModuleDef{
+			"Patient": {Tags: []TagUsage{
+				{Path: []string{"(0010,0010)"}, Type: "2"},
+				{Path: []string{"(0010,0020)"}, Type: "2 "},
+				{Path: []string{"(0010,0030)"}, Type: "3"},
+			}},
+			"Old": {Tags: []TagUsage{{Path: []string{"(0008,0070)"}, Type: "2"}}},
+		},
+		TagDefs: map[string]TagDef{
+			"(0010,0010)": {Keyword: "PatientName", VR: []string{"PN"}, VM: MustParseVM("1")},
+			"(0010,0020)": {Keyword: "PatientID", VR: []string{"LO"}, VM: MustParseVM("1")},
+			"(0010,0030)": {Keyword: "PatientBirthDate", VR: []string{"DA"}, VM: MustParseVM("1")},
+			"(0028,0106)": {Keyword: "SmallestImagePixelValue", VR: []string{"US"}, VM: MustParseVM("1")},
+		},
+	}
+	b := &SchemaDef{
+		ClassDefs: []ClassDef{
+			{SOPClassUid: "1.2.3.1", Name: "Kept Image Storage", Modules: []ModuleUsage{{Name: "Patient", Usage: "M"}, {Name: "Image", Usage: "M"}, {Name: "New", Usage: "U"}}},
+			{SOPClassUid: "1.2.3.3", Name: "Added Image Storage", Modules: []ModuleUsage{{Name: "Patient", Usage: "M"}}},
+		},
+		ModuleDefs: map[string]ModuleDef{
+			"Patient": {Tags: []TagUsage{
+				{Path: []string{"(0010,0010)"}, Type: "2"},
+				{Path: []string{"(0010,0020)"}, Type: "1"},
+				{Path: []string{"(0010,1002)"}, Type: "3"},
+				{Path: []string{"(0010,1002)", "(0010,0020)"}, Type: "1"},
+			}},
+			"New": {Tags: []TagUsage{{Path: []string{"(0008,0070)"}, Type: "2"}}},
+		},
+		TagDefs: map[string]TagDef{
+			"(0010,0010)": {Keyword: "PatientName", VR: []string{"PN"}, VM: MustParseVM("1")},
+			"(0010,0020)": {Keyword: "PatientIdentifier", VR: []string{"LO"}, VM: MustParseVM("1")},
+			"(0010,0030)": {Keyword: "PatientBirthDate", VR: []string{"DA"}, VM: MustParseVM("1-n")},
+			"(0028,0106)": {Keyword: "SmallestImagePixelValue", VR: []string{"US", "SS"}, VM: MustParseVM("1")},
+			"(0010,1002)": {Keyword: "OtherPatientIDsSequence", VR: []string{"SQ"}, VM: MustParseVM("1")},
+		},
+	}
+	return a, b
+}
+
+func TestDiff(t *testing.T) {
+	a, b := testDiffSchemas()
+	d := Diff(a, b)
+
+	classUIDs := func(cds []ClassDef) []string {
+		uids := []string{}
+		for _, cd := range cds {
+			uids = append(uids, cd.SOPClassUid)
+		}
+		return uids
+	}
+	if got := classUIDs(d.AddedClasses); !reflect.DeepEqual(got, []string{"1.2.3.3"}) {
+		t.Errorf("AddedClasses = %v, want [1.2.3.3]", got)
+	}
+	if got := classUIDs(d.RemovedClasses); !reflect.DeepEqual(got, []string{"1.2.3.2"}) {
+		t.Errorf("RemovedClasses = %v, want [1.2.3.2]", got)
+	}
+
+	wantClass := []ClassDiff{{
+		SOPClassUid:    "1.2.3.1",
+		Name:           "Kept Image Storage",
+		AddedModules:   []ModuleUsage{{Name: "New", Usage: "U"}},
+		RemovedModules: []ModuleUsage{{Name: "Old", Usage: "M"}},
+		ChangedModules: []ModuleUsageChange{{Name: "Image", From: "U", To: "M"}},
+	}}
+	if !reflect.DeepEqual(d.ChangedClasses, wantClass) {
+		t.Errorf("ChangedClasses = %+v, want %+v", d.ChangedClasses, wantClass)
+	}
+
+	// The modules are sorted by name and the type changes ignore the padding
+	wantModules := []ModuleDiff{
+		{Name: "New", AddedTags: []TagUsage{{Path: []string{"(0008,0070)"}, Type: "2"}}},
+		{Name: "Old", RemovedTags: []TagUsage{{Path: []string{"(0008,0070)"}, Type: "2"}}},
+		{
+			Name: "Patient",
+			AddedTags: []TagUsage{
+				{Path: []string{"(0010,1002)"}, Type: "3"},
+				{Path: []string{"(0010,1002)", "(0010,0020)"}, Type: "1"},
+			},
+			RemovedTags: []TagUsage{{Path: []string{"(0010,0030)"}, Type: "3"}},
+			RetypedTags: []TagUsageChange{{Path: []string{"(0010,0020)"}, From: "2", To: "1"}},
+		},
+	}
+	if !reflect.DeepEqual(d.ChangedModules, wantModules) {
+		t.Errorf("ChangedModules = %+v, want %+v", d.ChangedModules, wantModules)
+	}
+
+	if len(d.AddedTags) != 1 || d.AddedTags[0].Tag != "(0010,1002)" || d.AddedTags[0].To.Keyword != "OtherPatientIDsSequence" {
+		t.Errorf("AddedTags = %+v, want (0010,1002)", d.AddedTags)
+	}
+	if len(d.RemovedTags) != 0 {
+		t.Errorf("RemovedTags = %+v, want none", d.RemovedTags)
+	}
+
+	wantFields := map[string][]string{
+		"(0010,0020)": {"Keyword"},
+		"(0010,0030)": {"VM"},
+		"(0028,0106)": {"VR"},
+	}
+	tags := []string{}
+	for _, tc := range d.ChangedTags {
+		tags = append(tags, tc.Tag)
+		if !reflect.DeepEqual(tc.Fields, wantFields[tc.Tag]) {
+			t.Errorf("ChangedTags[%s].Fields = %v, want %v", tc.Tag, tc.Fields, wantFields[tc.Tag])
+		}
+	}
+	if !reflect.DeepEqual(tags, []string{"(0010,0020)", "(0010,0030)", "(0028,0106)"}) {
+		t.Errorf("ChangedTags = %v, want the sorted changed tags", tags)
+	}
+
+	if d.Empty() {
+		t.Errorf("Empty() = true for different schemas")
+	}
+}
+
+func TestDiffSame(t *testing.T) {
+	a, _ := testDiffSchemas()
+	if d := Diff(a, a); !d.Empty() {
+		t.Errorf("Diff of the same schema = %+v, want no changes", d)
+	}
+}
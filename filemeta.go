@@ -0,0 +1,238 @@
+package dicom
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// The module name used for the issues of the File Meta Information
+const fileMetaModule = "File Meta Information"
+
+// The elements of the File Meta Information with their types from PS3.10, for schemas crawled
+// before they were recorded
+var defaultFileMeta = []TagUsage{
+	{Path: []string{"(0002,0000)"}, Type: "1"},
+	{Path: []string{"(0002,0001)"}, Type: "1"},
+	{Path: []string{"(0002,0002)"}, Type: "1"},
+	{Path: []string{"(0002,0003)"}, Type: "1"},
+	{Path: []string{"(0002,0010)"}, Type: "1"},
+	{Path: []string{"(0002,0012)"}, Type: "1"},
+	{Path: []string{"(0002,0013)"}, Type: "3"},
+	{Path: []string{"(0002,0016)"}, Type: "3"},
+	{Path: []string{"(0002,0017)"}, Type: "3"},
+	{Path: []string{"(0002,0018)"}, Type: "3"},
+	{Path: []string{"(0002,0100)"}, Type: "3"},
+	{Path: []string{"(0002,0102)"}, Type: "1C"},
+}
+
+// The VRs with a 4 byte length in the explicit VR encoding
+var longLengthVRs = map[string]bool{"OB": true, "OD": true, "OF": true, "OL": true, "OV": true, "OW": true, "SQ": true, "SV": true, "UC": true, "UN": true, "UR": true, "UT": true, "UV": true}
+
+// The largest File Meta Information element that is read, a larger length means that the file is corrupt
+const maxFileMetaElementLength = 1 << 20
+
+// A raw element of the File Meta Information
+type fileMetaElement struct {
+	tag   dicomtag.Tag
+	vr    string
+	value []byte
+}
+
+// ValidateFileMeta checks the header of a DICOM file (see PS3.10 Section 7.1) from the start of the
+// file, which is where most broken files fail before their dataset can even be parsed:
+//
+//	The file must start with the 128 byte preamble followed by the "DICM" prefix.
+//	The File Meta Information elements (group 0002) must be encoded with explicit VR little endian.
+//	Type 1 elements must be present with a value and Type 2 elements must be present.
+//	The File Meta Information Group Length (0002,0000) must be the length of the rest of the group.
+//	The File Meta Information Version (0002,0001) must be 00H 01H.
+//	The Transfer Syntax UID (0002,0010) must be a known transfer syntax.
+//	The VR of the elements must match the schema, if it has the tag definitions of group 0002.
+//
+// The types of the elements are taken from the schema, or from PS3.10 if the schema doesn't have
+// them. The reader is buffered, so it may be read past the File Meta Information. The SOPClassUID
+// of the report is the Media Storage SOP Class UID.
+func ValidateFileMeta(r io.Reader, schema *SchemaDef) ValidationReport {
+	report := ValidationReport{}
+	br := bufio.NewReader(r)
+
+	header := make([]byte, 132)
+	n, err := io.ReadFull(br, header)
+	if n >= 4 && string(header[:4]) == "DICM" {
+		report.add(SeverityError, IssueInvalidFileMeta, fileMetaModule, nil, "There is no preamble before the DICM prefix")
+		return report
+	}
+	if err != nil {
+		report.add(SeverityError, IssueInvalidFileMeta, fileMetaModule, nil, "The file is too short for the preamble and DICM prefix: %v", err)
+		return report
+	}
+	if string(header[128:]) != "DICM" {
+		report.add(SeverityError, IssueInvalidFileMeta, fileMetaModule, nil, "The DICM prefix is not present after the preamble")
+		return report
+	}
+
+	elems := []fileMetaElement{}
+	groupBytes := 0
+	for {
+		b, err := br.Peek(2)
+		if err != nil || binary.LittleEndian.Uint16(b) != dicomtag.MetadataGroup {
+			break
+		}
+
+		e, size, err := readFileMetaElement(br)
+		if err != nil {
+			report.add(SeverityError, IssueInvalidFileMeta, fileMetaModule, nil, "%v", err)
+			return report
+		}
+
+		if e.tag.Element != 0x0000 {
+			groupBytes += size
+		}
+		elems = append(elems, e)
+	}
+
+	if len(elems) == 0 {
+		report.add(SeverityError, IssueInvalidFileMeta, fileMetaModule, nil, "There are no File Meta Information elements after the DICM prefix")
+		return report
+	}
+
+	found := map[dicomtag.Tag]fileMetaElement{}
+	for _, e := range elems {
+		found[e.tag] = e
+	}
+	report.SOPClassUID = fileMetaString(found[dicomtag.MediaStorageSOPClassUID])
+
+	usages := defaultFileMeta
+	var patterns []patternDef
+	if schema != nil {
+		if len(schema.FileMeta) > 0 {
+			usages = schema.FileMeta
+		}
+		patterns = tagPatterns(schema)
+	}
+
+	for _, tu := range usages {
+		t, err := ParseTag(tu.Path[0])
+		if err != nil {
+			continue
+		}
+
+		typ := strings.TrimSpace(tu.Type)
+		e, ok := found[t]
+		if !ok {
+			if typ == "1" || typ == "2" {
+				report.add(SeverityError, IssueMissingAttribute, fileMetaModule, tu.Path, "Type %s element %s is not present", typ, fileMetaKeyword(schema, patterns, t))
+			}
+			continue
+		}
+		if (typ == "1" || typ == "1C") && len(strings.TrimRight(string(e.value), "\x00 ")) == 0 {
+			report.add(SeverityError, IssueEmptyAttribute, fileMetaModule, tu.Path, "Type %s element %s has no value", typ, fileMetaKeyword(schema, patterns, t))
+		}
+	}
+
+	if e, ok := found[dicomtag.FileMetaInformationGroupLength]; ok {
+		path := []string{e.tag.String()}
+		if elems[0].tag != e.tag {
+			report.add(SeverityError, IssueInvalidFileMeta, fileMetaModule, path, "FileMetaInformationGroupLength is not the first element")
+		}
+		if len(e.value) != 4 {
+			report.add(SeverityError, IssueInvalidFileMeta, fileMetaModule, path, "FileMetaInformationGroupLength has %d bytes, expected 4", len(e.value))
+		} else if gl := binary.LittleEndian.Uint32(e.value); int(gl) != groupBytes {
+			report.add(SeverityError, IssueInvalidFileMeta, fileMetaModule, path, "FileMetaInformationGroupLength is %d, but the group has %d bytes", gl, groupBytes)
+		}
+	}
+
+	if e, ok := found[dicomtag.FileMetaInformationVersion]; ok && (len(e.value) != 2 || e.value[0] != 0x00 || e.value[1] != 0x01) {
+		report.add(SeverityError, IssueInvalidFileMeta, fileMetaModule, []string{e.tag.String()}, "FileMetaInformationVersion is % x, expected 00 01", e.value)
+	}
+
+	if e, ok := found[dicomtag.TransferSyntaxUID]; ok {
+		if uid := fileMetaString(e); uid != "" {
+			lookup := LookupTransferSyntax
+			if schema != nil {
+				lookup = schema.LookupTransferSyntax
+			}
+			if _, ok := lookup(uid); !ok {
+				report.add(SeverityError, IssueInvalidTransferSyntax, fileMetaModule, []string{e.tag.String()}, "%q is not a known transfer syntax", uid)
+			}
+		}
+	}
+
+	if schema != nil {
+		for _, e := range elems {
+			td, ok := lookupTag(schema, patterns, e.tag)
+			if !ok || len(td.VR) == 0 || td.VR[0] == "" {
+				continue
+			}
+			match := false
+			for _, vr := range td.VR {
+				match = match || vr == e.vr
+			}
+			if !match {
+				report.add(SeverityError, IssueInvalidVR, fileMetaModule, []string{e.tag.String()}, "%s has VR %s, expected %s", td.Keyword, e.vr, strings.Join(td.VR, " or "))
+			}
+		}
+	}
+
+	return report
+}
+
+// Read an explicit VR little endian element, providing its encoded size
+func readFileMetaElement(r io.Reader) (fileMetaElement, int, error) {
+	e := fileMetaElement{}
+
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return e, 0, fmt.Errorf("Unable to read the element header: %v", err)
+	}
+	e.tag = dicomtag.Tag{Group: binary.LittleEndian.Uint16(head[0:2]), Element: binary.LittleEndian.Uint16(head[2:4])}
+	e.vr = string(head[4:6])
+
+	if head[4] < 'A' || head[4] > 'Z' || head[5] < 'A' || head[5] > 'Z' {
+		return e, 0, fmt.Errorf("%s is not encoded with an explicit VR", e.tag)
+	}
+
+	size := 8
+	length := uint32(binary.LittleEndian.Uint16(head[6:8]))
+	if longLengthVRs[e.vr] {
+		long := make([]byte, 4)
+		if _, err := io.ReadFull(r, long); err != nil {
+			return e, 0, fmt.Errorf("Unable to read the length of %s: %v", e.tag, err)
+		}
+		size += 4
+		length = binary.LittleEndian.Uint32(long)
+	}
+
+	if length > maxFileMetaElementLength {
+		return e, 0, fmt.Errorf("%s has a length of %d, which is too long for File Meta Information", e.tag, length)
+	}
+
+	e.value = make([]byte, length)
+	if _, err := io.ReadFull(r, e.value); err != nil {
+		return e, 0, fmt.Errorf("Unable to read the value of %s: %v", e.tag, err)
+	}
+
+	return e, size + int(length), nil
+}
+
+// The string value of an element without its padding
+func fileMetaString(e fileMetaElement) string {
+	return strings.TrimRight(string(e.value), "\x00 ")
+}
+
+func fileMetaKeyword(schema *SchemaDef, patterns []patternDef, t dicomtag.Tag) string {
+	if schema != nil {
+		if td, ok := lookupTag(schema, patterns, t); ok && td.Keyword != "" {
+			return td.Keyword
+		}
+	}
+	if ti, err := dicomtag.Find(t); err == nil {
+		return ti.Name
+	}
+	return t.String()
+}
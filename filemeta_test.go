@@ -0,0 +1,143 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// The File Meta Information elements of an MR image with the Explicit VR Little Endian transfer syntax
+func testFileMetaElements() []fileMetaElement {
+	return []fileMetaElement{
+		{dicomtag.FileMetaInformationVersion, "OB", []byte{0x00, 0x01}},
+		{dicomtag.MediaStorageSOPClassUID, "UI", []byte("1.2.840.10008.5.1.4.1.1.4\x00")},
+		{dicomtag.MediaStorageSOPInstanceUID, "UI", []byte("1.2.3.4.5\x00")},
+		{dicomtag.TransferSyntaxUID, "UI", []byte("1.2.840.10008.1.2.1\x00")},
+		{dicomtag.ImplementationClassUID, "UI", []byte("1.2.3.4\x00")},
+	}
+}
+
+// Prepend the File Meta Information Group Length with the length of the elements
+func withGroupLength(elems []fileMetaElement) []fileMetaElement {
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(encodeFileMeta(elems))))
+	return append([]fileMetaElement{{dicomtag.FileMetaInformationGroupLength, "UL", length}}, elems...)
+}
+
+// Encode the elements with explicit VR little endian
+func encodeFileMeta(elems []fileMetaElement) []byte {
+	buf := &bytes.Buffer{}
+	for _, e := range elems {
+		binary.Write(buf, binary.LittleEndian, []uint16{e.tag.Group, e.tag.Element})
+		buf.WriteString(e.vr)
+		if longLengthVRs[e.vr] {
+			binary.Write(buf, binary.LittleEndian, []uint16{0})
+			binary.Write(buf, binary.LittleEndian, uint32(len(e.value)))
+		} else {
+			binary.Write(buf, binary.LittleEndian, uint16(len(e.value)))
+		}
+		buf.Write(e.value)
+	}
+	return buf.Bytes()
+}
+
+// A file with the preamble, the DICM prefix and the elements
+func testFile(elems []fileMetaElement) []byte {
+	return append(append(make([]byte, 128), "DICM"...), encodeFileMeta(elems)...)
+}
+
+func TestValidateFileMeta(t *testing.T) {
+	// The elements with one of them replaced, or left out if the replacement is nil
+	replace := func(tag dicomtag.Tag, e *fileMetaElement) []fileMetaElement {
+		elems := []fileMetaElement{}
+		for _, fe := range testFileMetaElements() {
+			if fe.tag != tag {
+				elems = append(elems, fe)
+			} else if e != nil {
+				elems = append(elems, *e)
+			}
+		}
+		return elems
+	}
+	// A dataset element that follows the File Meta Information
+	dataset := encodeFileMeta([]fileMetaElement{{dicomtag.PatientID, "LO", []byte("12345 ")}})
+
+	valid := testFile(withGroupLength(testFileMetaElements()))
+	wrongLength := withGroupLength(testFileMetaElements())
+	wrongLength[0].value = []byte{0x10, 0x00, 0x00, 0x00}
+	notFirst := withGroupLength(testFileMetaElements())
+	notFirst[0], notFirst[1] = notFirst[1], notFirst[0]
+	implicit := append(testFile(nil), 0x02, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x01)
+
+	tests := []struct {
+		name string
+		file []byte
+		want []IssueKind
+	}{
+		{"valid", valid, nil},
+		{"dataset after the header", append(append([]byte{}, valid...), dataset...), nil},
+		{"no preamble", valid[128:], []IssueKind{IssueInvalidFileMeta}},
+		{"too short", valid[:100], []IssueKind{IssueInvalidFileMeta}},
+		{"no DICM prefix", append(make([]byte, 132), valid[132:]...), []IssueKind{IssueInvalidFileMeta}},
+		{"no elements", append(testFile(nil), dataset...), []IssueKind{IssueInvalidFileMeta}},
+		{"implicit VR", implicit, []IssueKind{IssueInvalidFileMeta}},
+		{"truncated element", valid[:len(valid)-3], []IssueKind{IssueInvalidFileMeta}},
+		{"missing element", testFile(withGroupLength(replace(dicomtag.TransferSyntaxUID, nil))), []IssueKind{IssueMissingAttribute}},
+		{"missing group length", testFile(testFileMetaElements()), []IssueKind{IssueMissingAttribute}},
+		{"empty element", testFile(withGroupLength(replace(dicomtag.ImplementationClassUID,
+			&fileMetaElement{dicomtag.ImplementationClassUID, "UI", []byte{0x00, 0x00}}))), []IssueKind{IssueEmptyAttribute}},
+		{"wrong group length", testFile(wrongLength), []IssueKind{IssueInvalidFileMeta}},
+		{"group length not first", testFile(notFirst), []IssueKind{IssueInvalidFileMeta}},
+		{"wrong version", testFile(withGroupLength(replace(dicomtag.FileMetaInformationVersion,
+			&fileMetaElement{dicomtag.FileMetaInformationVersion, "OB", []byte{0x00, 0x02}}))), []IssueKind{IssueInvalidFileMeta}},
+		{"unknown transfer syntax", testFile(withGroupLength(replace(dicomtag.TransferSyntaxUID,
+			&fileMetaElement{dicomtag.TransferSyntaxUID, "UI", []byte("1.2.3.4\x00")}))), []IssueKind{IssueInvalidTransferSyntax}},
+	}
+
+	for _, tt := range tests {
+		report := ValidateFileMeta(bytes.NewReader(tt.file), nil)
+
+		kinds := []IssueKind{}
+		for _, vi := range report.Issues {
+			kinds = append(kinds, vi.Kind)
+		}
+		if len(tt.want) == 0 {
+			tt.want = []IssueKind{}
+		}
+		if !reflect.DeepEqual(kinds, tt.want) {
+			t.Errorf("%s: ValidateFileMeta issues = %v, want %v", tt.name, report.Issues, tt.want)
+		}
+	}
+
+	if report := ValidateFileMeta(bytes.NewReader(valid), nil); report.SOPClassUID != "1.2.840.10008.5.1.4.1.1.4" {
+		t.Errorf("ValidateFileMeta SOPClassUID = %q, want the MediaStorageSOPClassUID", report.SOPClassUID)
+	}
+}
+
+func TestValidateFileMetaSchema(t *testing.T) {
+	// The types and VRs of the schema are used instead of those of PS3.10
+	schema := &SchemaDef{
+		FileMeta: []TagUsage{
+			{Path: []string{"(0002,0010)"}, Type: "1"},
+			{Path: []string{"(0002,0013)"}, Type: "1"},
+		},
+		TagDefs: map[string]TagDef{
+			"(0002,0002)": {Keyword: "MediaStorageSOPClassUID", VR: []string{"UI"}},
+			"(0002,0012)": {Keyword: "ImplementationClassUID", VR: []string{"LO"}},
+		},
+	}
+
+	report := ValidateFileMeta(bytes.NewReader(testFile(testFileMetaElements())), schema)
+
+	want := []IssueKind{IssueMissingAttribute, IssueInvalidVR}
+	kinds := []IssueKind{}
+	for _, vi := range report.Issues {
+		kinds = append(kinds, vi.Kind)
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("ValidateFileMeta issues = %v, want %v", report.Issues, want)
+	}
+}
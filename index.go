@@ -0,0 +1,116 @@
+package dicom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// A SchemaIndex is a read-only view of a SchemaDef with indexes for the common lookups
+// so that they don't require a scan of the class, tag or module definitions.
+type SchemaIndex struct {
+	*SchemaDef
+
+	tagsByKeyword   map[string]dicomtag.Tag
+	classesByUID    map[string]*ClassDef
+	classesByName   map[string]*ClassDef
+	classesByModule map[string][]*ClassDef
+	patterns        []patternDef
+}
+
+// NewSchemaIndex builds the indexes for a schema. The schema must not be modified afterwards
+// since the indexes are not updated.
+func NewSchemaIndex(schema *SchemaDef) *SchemaIndex {
+	si := &SchemaIndex{
+		SchemaDef:       schema,
+		tagsByKeyword:   map[string]dicomtag.Tag{},
+		classesByUID:    map[string]*ClassDef{},
+		classesByName:   map[string]*ClassDef{},
+		classesByModule: map[string][]*ClassDef{},
+		patterns:        tagPatterns(schema),
+	}
+
+	for key, td := range schema.TagDefs {
+		p, err := ParseTagPattern(key)
+		if err != nil {
+			continue
+		}
+		// Repeating groups share a keyword, favour the lowest tag for a stable result, which is the
+		// first tag of a pattern
+		t := dicomtag.Tag{Group: p.Group, Element: p.Element}
+		if prev, ok := si.tagsByKeyword[td.Keyword]; ok && prev.Compare(t) < 0 {
+			continue
+		}
+		si.tagsByKeyword[td.Keyword] = t
+	}
+
+	for i := range schema.ClassDefs {
+		cd := &schema.ClassDefs[i]
+		si.classesByUID[cd.SOPClassUid] = cd
+		si.classesByName[cd.Name] = cd
+
+		for _, mu := range cd.Modules {
+			si.classesByModule[mu.Name] = append(si.classesByModule[mu.Name], cd)
+		}
+	}
+
+	return si
+}
+
+// TagByKeyword looks up a tag definition by its keyword (e.g. "PatientID").
+func (si *SchemaIndex) TagByKeyword(keyword string) (dicomtag.Tag, TagDef, bool) {
+	t, ok := si.tagsByKeyword[keyword]
+	if !ok {
+		return dicomtag.Tag{}, TagDef{}, false
+	}
+
+	td, ok := si.TagByTag(t)
+	return t, td, ok
+}
+
+// TagByTag looks up a tag definition by its tag. Tags of repeating groups and other ranges
+// (e.g. (6002,3000)) are matched against the patterns of the schema (e.g. "(60xx,3000)").
+func (si *SchemaIndex) TagByTag(t dicomtag.Tag) (TagDef, bool) {
+	return lookupTag(si.SchemaDef, si.patterns, t)
+}
+
+// ClassByUID looks up the SOP Class definition with the provided SOP Class UID.
+func (si *SchemaIndex) ClassByUID(uid string) (*ClassDef, bool) {
+	cd, ok := si.classesByUID[uid]
+	return cd, ok
+}
+
+// ClassByName looks up the SOP Class definition with the provided name
+// (e.g. "MR Image Storage").
+func (si *SchemaIndex) ClassByName(name string) (*ClassDef, bool) {
+	cd, ok := si.classesByName[name]
+	return cd, ok
+}
+
+// ClassesUsingModule provides the SOP Class definitions that include the named module
+// in any usage (mandatory, conditional or user optional).
+func (si *SchemaIndex) ClassesUsingModule(module string) []*ClassDef {
+	return si.classesByModule[module]
+}
+
+// ParseTag parses a tag in the string form used as keys and paths in the schema
+// (e.g. "(0008,001a)"). The parentheses are optional and the hex digits are case insensitive.
+func ParseTag(s string) (dicomtag.Tag, error) {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(s), "()"), ",")
+	if len(parts) != 2 {
+		return dicomtag.Tag{}, fmt.Errorf("Invalid tag %q", s)
+	}
+
+	group, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 16, 16)
+	if err != nil {
+		return dicomtag.Tag{}, fmt.Errorf("Invalid tag group in %q: %v", s, err)
+	}
+	element, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 16, 16)
+	if err != nil {
+		return dicomtag.Tag{}, fmt.Errorf("Invalid tag element in %q: %v", s, err)
+	}
+
+	return dicomtag.Tag{Group: uint16(group), Element: uint16(element)}, nil
+}
@@ -0,0 +1,125 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+func testIndexSchema() *SchemaDef {
+	return &SchemaDef{
+		ClassDefs: []ClassDef{
+			{SOPClassUid: "1.2.3.1", Name: "First Image Storage", Modules: []ModuleUsage{{Name: "Patient", Usage: "M"}, {Name: "First", Usage: "M"}}},
+			{SOPClassUid: "1.2.3.2", Name: "Second Image Storage", Modules: []ModuleUsage{{Name: "Patient", Usage: "M"}, {Name: "Second", Usage: "U"}}},
+		},
+		TagDefs: map[string]TagDef{
+			"(0010,0010)": {Keyword: "PatientName"},
+			"(0008,001a)": {Keyword: "RelatedGeneralSOPClassUID"},
+			"(60xx,3000)": {Keyword: "OverlayData"},
+		},
+		ModuleDefs: map[string]ModuleDef{
+			"Patient": {Tags: []TagUsage{{Path: []string{"(0010,0010)"}, Type: "2"}}},
+			"First":   {Tags: []TagUsage{{Path: []string{"(0008,001a)"}, Type: "3"}}},
+			"Second":  {Tags: []TagUsage{{Path: []string{"(60xx,3000)"}, Type: "1"}}},
+		},
+	}
+}
+
+func TestSchemaIndexTags(t *testing.T) {
+	si := NewSchemaIndex(testIndexSchema())
+
+	tests := []struct {
+		keyword string
+		tag     dicomtag.Tag
+	}{
+		{"PatientName", dicomtag.Tag{Group: 0x0010, Element: 0x0010}},
+		{"RelatedGeneralSOPClassUID", dicomtag.Tag{Group: 0x0008, Element: 0x001a}},
+		// The first tag of a repeating group
+		{"OverlayData", dicomtag.Tag{Group: 0x6000, Element: 0x3000}},
+	}
+
+	for _, tt := range tests {
+		tag, td, ok := si.TagByKeyword(tt.keyword)
+		if !ok || tag != tt.tag || td.Keyword != tt.keyword {
+			t.Errorf("TagByKeyword(%q) = %v, %q, %v, want %v", tt.keyword, tag, td.Keyword, ok, tt.tag)
+		}
+		if td, ok := si.TagByTag(tt.tag); !ok || td.Keyword != tt.keyword {
+			t.Errorf("TagByTag(%v) = %q, %v, want %q", tt.tag, td.Keyword, ok, tt.keyword)
+		}
+	}
+
+	if td, ok := si.TagByTag(dicomtag.Tag{Group: 0x6002, Element: 0x3000}); !ok || td.Keyword != "OverlayData" {
+		t.Errorf("TagByTag((6002,3000)) = %q, %v, want OverlayData", td.Keyword, ok)
+	}
+	if _, _, ok := si.TagByKeyword("PatientID"); ok {
+		t.Errorf("TagByKeyword(PatientID) found a tag that isn't in the schema")
+	}
+	if _, ok := si.TagByTag(dicomtag.Tag{Group: 0x0010, Element: 0x0020}); ok {
+		t.Errorf("TagByTag((0010,0020)) found a tag that isn't in the schema")
+	}
+}
+
+func TestSchemaIndexClasses(t *testing.T) {
+	si := NewSchemaIndex(testIndexSchema())
+
+	if cd, ok := si.ClassByUID("1.2.3.2"); !ok || cd.Name != "Second Image Storage" {
+		t.Errorf("ClassByUID(1.2.3.2) = %v, %v, want Second Image Storage", cd, ok)
+	}
+	if cd, ok := si.ClassByName("First Image Storage"); !ok || cd.SOPClassUid != "1.2.3.1" {
+		t.Errorf("ClassByName(First Image Storage) = %v, %v, want 1.2.3.1", cd, ok)
+	}
+	if _, ok := si.ClassByUID("1.2.3.3"); ok {
+		t.Errorf("ClassByUID(1.2.3.3) found a class that isn't in the schema")
+	}
+
+	tests := []struct {
+		module string
+		want   []string
+	}{
+		{"Patient", []string{"1.2.3.1", "1.2.3.2"}},
+		{"Second", []string{"1.2.3.2"}},
+		{"Unknown", nil},
+	}
+
+	for _, tt := range tests {
+		cds := si.ClassesUsingModule(tt.module)
+		uids := []string{}
+		for _, cd := range cds {
+			uids = append(uids, cd.SOPClassUid)
+		}
+		if len(uids) != len(tt.want) {
+			t.Errorf("ClassesUsingModule(%q) = %v, want %v", tt.module, uids, tt.want)
+			continue
+		}
+		for i := range uids {
+			if uids[i] != tt.want[i] {
+				t.Errorf("ClassesUsingModule(%q) = %v, want %v", tt.module, uids, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		in   string
+		want dicomtag.Tag
+	}{
+		{"(0008,001a)", dicomtag.Tag{Group: 0x0008, Element: 0x001a}},
+		{"(0008,001A)", dicomtag.Tag{Group: 0x0008, Element: 0x001a}},
+		{"0008,001a", dicomtag.Tag{Group: 0x0008, Element: 0x001a}},
+		{" (7FE0, 0010) ", dicomtag.Tag{Group: 0x7fe0, Element: 0x0010}},
+	}
+
+	for _, tt := range tests {
+		if got, err := ParseTag(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseTag(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "(0008)", "(0008,001a,0001)", "(g008,001a)", "(10008,001a)"} {
+		if got, err := ParseTag(in); err == nil {
+			t.Errorf("ParseTag(%q) = %v, want an error", in, got)
+		}
+	}
+}
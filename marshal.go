@@ -0,0 +1,211 @@
+package dicom
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+	"github.com/macadamian/dicom/dicomvalue"
+)
+
+// Marshal a Go value into a DICOM dataset. This is the inverse of Unmarshal and it expects the
+// same shape of storage class struct (see Unmarshal), such as a dicom2019b MRImageStorage or a
+// pointer to one.
+//
+// Each module of the storage class is walked and its fields with a "tag" struct tag become
+// elements with the VR from the "vr" struct tag. Optional modules and fields that are nil pointers
+// or nil slices are left out of the dataset, while other fields are always included so that Type 2
+// attributes are present even when they are empty. Sequence fields become SQ elements with an item
+// for each struct in the slice. Values that implement encoding.TextMarshaler, such as the
+// dicomvalue types, are written in their text form and numbers are formatted for DS and IS fields.
+//
+// The SOPClassUID element is taken from the storage class if none of the modules provide a value. The
+// elements are sorted by tag at each level and an element is only included once if more than one
+// module has a field for the same tag. File meta information (group 0002) is not added.
+func Marshal(v interface{}) (*dicom.DataSet, error) {
+	scv := reflect.ValueOf(v)
+	if scv.Kind() == reflect.Ptr {
+		if scv.IsNil() {
+			return nil, fmt.Errorf("Must provide a storage value to marshal, but it was nil")
+		}
+		scv = scv.Elem()
+	}
+	sct := scv.Type()
+
+	if sct.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Must provide a storage class struct to do anything meaningful")
+	}
+
+	soif, ok := sct.FieldByName("SOPClassUID")
+	if !ok {
+		return nil, fmt.Errorf("Provided struct is not a storage class struct. It is missing the SOPClassUID property")
+	}
+
+	elems := elementSet{}
+
+	for i := 0; i < sct.NumField(); i++ {
+		modv := scv.Field(i)
+
+		if modv.Kind() == reflect.Ptr {
+			if modv.IsNil() {
+				continue
+			}
+			modv = modv.Elem()
+		}
+
+		if modv.Kind() != reflect.Struct {
+			continue
+		}
+
+		if err := elems.addStruct(modv); err != nil {
+			return nil, fmt.Errorf("Unable to marshal module %s: %v", sct.Field(i).Name, err)
+		}
+	}
+
+	if sce, ok := elems[dicomtag.SOPClassUID]; !ok {
+		elems.add(&dicom.Element{Tag: dicomtag.SOPClassUID, VR: "UI", Value: []interface{}{string(soif.Tag)}})
+	} else if elementEmpty(sce) {
+		sce.Value = []interface{}{string(soif.Tag)}
+	}
+
+	return &dicom.DataSet{Elements: elems.sorted()}, nil
+}
+
+// A set of elements at one level of a dataset (top-level or an item) with at most one element per tag
+type elementSet map[dicomtag.Tag]*dicom.Element
+
+func (es elementSet) has(t dicomtag.Tag) bool {
+	_, ok := es[t]
+	return ok
+}
+
+func (es elementSet) add(e *dicom.Element) {
+	if !es.has(e.Tag) {
+		es[e.Tag] = e
+	}
+}
+
+func (es elementSet) sorted() []*dicom.Element {
+	elems := make([]*dicom.Element, 0, len(es))
+	for _, e := range es {
+		elems = append(elems, e)
+	}
+	sort.Slice(elems, func(i, j int) bool { return elems[i].Tag.Compare(elems[j].Tag) < 0 })
+	return elems
+}
+
+// Add an element for each of the tagged fields of a module or sequence struct
+func (es elementSet) addStruct(sv reflect.Value) error {
+	st := sv.Type()
+
+	for j := 0; j < st.NumField(); j++ {
+		tagf := st.Field(j)
+		tagstr := tagf.Tag.Get("tag")
+		if tagstr == "" {
+			continue
+		}
+
+		t, err := ParseTag(tagstr)
+		if err != nil {
+			return fmt.Errorf("Field %s has an invalid tag: %v", tagf.Name, err)
+		}
+
+		if es.has(t) {
+			continue
+		}
+
+		e, err := marshalElement(t, tagf.Tag.Get("vr"), sv.Field(j))
+		if err != nil {
+			return fmt.Errorf("Unable to marshal %s: %v", tagf.Name, err)
+		}
+		if e != nil {
+			es.add(e)
+		}
+	}
+
+	return nil
+}
+
+// Build the element for the value of a tagged field, or nil if the field should be left out
+func marshalElement(t dicomtag.Tag, vr string, fv reflect.Value) (*dicom.Element, error) {
+	e := &dicom.Element{Tag: t, VR: vr, Value: []interface{}{}}
+
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return nil, nil
+		}
+		return marshalElement(t, vr, fv.Elem())
+	case reflect.Slice:
+		if fv.IsNil() {
+			return nil, nil
+		}
+		// Byte slices are a single value for the OB/OW family of VRs
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			e.Value = append(e.Value, fv.Interface())
+			return e, nil
+		}
+		fallthrough
+	case reflect.Array:
+		for k := 0; k < fv.Len(); k++ {
+			ev := fv.Index(k)
+
+			if ev.Kind() == reflect.Struct && vr == "SQ" {
+				items := elementSet{}
+				if err := items.addStruct(ev); err != nil {
+					return nil, err
+				}
+				e.Value = append(e.Value, &dicom.Element{Tag: dicomtag.Item, VR: "NA", Value: elementValues(items.sorted())})
+				continue
+			}
+
+			v, err := marshalValue(vr, ev)
+			if err != nil {
+				return nil, err
+			}
+			e.Value = append(e.Value, v)
+		}
+	default:
+		v, err := marshalValue(vr, fv)
+		if err != nil {
+			return nil, err
+		}
+		e.Value = append(e.Value, v)
+	}
+
+	return e, nil
+}
+
+// Convert a single value of a field to the form expected by the DICOM writer, which is a string
+// for the values with a text form and for the numbers of DS and IS values.
+func marshalValue(vr string, v reflect.Value) (interface{}, error) {
+	if tm, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+
+	switch {
+	case vr == "DS" && (v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64):
+		return dicomvalue.FormatDS(v.Float()), nil
+	case vr == "IS" && v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		return dicomvalue.FormatIS(v.Int()), nil
+	case v.Kind() == reflect.String:
+		return v.String(), nil
+	}
+
+	return v.Interface(), nil
+}
+
+func elementValues(elems []*dicom.Element) []interface{} {
+	values := make([]interface{}, len(elems))
+	for i, e := range elems {
+		values[i] = e
+	}
+	return values
+}
@@ -0,0 +1,159 @@
+package dicom
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+	"github.com/macadamian/dicom/dicom2019b"
+	"github.com/macadamian/dicom/dicomvalue"
+)
+
+// An MR image with zero numbers in Type 1 and Type 2 attributes. The attributes that are in more
+// than one module have the same value in each of them, as they do after Unmarshal.
+func testMRImage(t *testing.T) *dicom2019b.MRImageStorage {
+	name, err := dicomvalue.ParsePersonName("Doe^John")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date, err := dicomvalue.ParseDate("20190315")
+	if err != nil {
+		t.Fatal(err)
+	}
+	instanceNumber := int64(7)
+	meaning := "Brain"
+	code := "T-A0100"
+
+	mr := &dicom2019b.MRImageStorage{}
+	mr.Patient.PatientName = name
+	mr.Patient.PatientID = "12345"
+	mr.GeneralStudy.StudyInstanceUID = "1.2.3.4.1"
+	mr.GeneralStudy.StudyDate = date
+	mr.GeneralSeries.Modality = "MR"
+	mr.GeneralSeries.SeriesInstanceUID = "1.2.3.4.2"
+	mr.FrameofReference.FrameOfReferenceUID = "1.2.3.4.3"
+	mr.GeneralEquipment.Manufacturer = "ACME"
+	mr.GeneralImage.InstanceNumber = instanceNumber
+	mr.GeneralImage.ImageType = []string{"ORIGINAL", "PRIMARY"}
+	mr.GeneralImage.AnatomicRegionSequence = []dicom2019b.AnatomicRegionSequence{{CodeValue: &code, CodeMeaning: meaning}}
+	mr.SOPCommon.InstanceNumber = &instanceNumber
+	mr.SOPCommon.SOPClassUID = "1.2.840.10008.5.1.4.1.1.4"
+	mr.SOPCommon.SOPInstanceUID = "1.2.3.4.4"
+
+	mr.ImagePlane.PixelSpacing = [2]float64{0.5, 0.5}
+	mr.ImagePlane.ImageOrientationPatient = [6]float64{1, 0, 0, 0, 1, 0}
+	// A position at the origin is a value like any other
+	mr.ImagePlane.ImagePositionPatient = [3]float64{0, 0, 0}
+
+	mr.ImagePixel.SamplesPerPixel = 1
+	mr.ImagePixel.PhotometricInterpretation = "MONOCHROME2"
+	mr.ImagePixel.Rows = 256
+	mr.ImagePixel.Columns = 256
+	mr.ImagePixel.BitsAllocated = 16
+	mr.ImagePixel.BitsStored = 12
+	mr.ImagePixel.HighBit = 11
+	// Unsigned pixels
+	mr.ImagePixel.PixelRepresentation = 0
+
+	mr.MRImage.ImageType = mr.GeneralImage.ImageType
+	mr.MRImage.AnatomicRegionSequence = mr.GeneralImage.AnatomicRegionSequence
+	mr.MRImage.SamplesPerPixel = mr.ImagePixel.SamplesPerPixel
+	mr.MRImage.PhotometricInterpretation = mr.ImagePixel.PhotometricInterpretation
+	mr.MRImage.BitsAllocated = mr.ImagePixel.BitsAllocated
+	mr.MRImage.BitsStored = mr.ImagePixel.BitsStored
+	mr.MRImage.HighBit = mr.ImagePixel.HighBit
+	mr.MRImage.ScanningSequence = []string{"SE"}
+	mr.MRImage.SequenceVariant = []string{"NONE"}
+	mr.MRImage.EchoTime = 15.5
+
+	return mr
+}
+
+func TestMarshalZeroValues(t *testing.T) {
+	ds, err := Marshal(testMRImage(t))
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	tests := []struct {
+		tag  dicomtag.Tag
+		want []interface{}
+	}{
+		// Type 1 and Type 2 numbers are written even when they are zero
+		{dicomtag.PixelRepresentation, []interface{}{uint16(0)}},
+		{dicomtag.ImagePositionPatient, []interface{}{"0", "0", "0"}},
+		{dicomtag.SeriesNumber, []interface{}{"0"}},
+		{dicomtag.EchoTrainLength, []interface{}{"0"}},
+		{dicomtag.EchoTime, []interface{}{"15.5"}},
+		// Only one element for the attributes that are in more than one module
+		{dicomtag.InstanceNumber, []interface{}{"7"}},
+	}
+
+	for _, tt := range tests {
+		e, err := ds.FindElementByTag(tt.tag)
+		if err != nil {
+			t.Errorf("Marshal did not write %v", tt.tag)
+			continue
+		}
+		if !reflect.DeepEqual(e.Value, tt.want) {
+			t.Errorf("Marshal %v = %#v, want %#v", tt.tag, e.Value, tt.want)
+		}
+	}
+
+	// The optional attributes that are nil pointers are left out
+	for _, tag := range []dicomtag.Tag{dicomtag.SliceLocation, dicomtag.PlanarConfiguration} {
+		if _, err := ds.FindElementByTag(tag); err == nil {
+			t.Errorf("Marshal wrote %v, which was not set", tag)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	mr := testMRImage(t)
+
+	ds, err := Marshal(mr)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+
+	got := &dicom2019b.MRImageStorage{}
+	if err := Unmarshal(ds, got); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(got, mr) {
+		t.Errorf("Unmarshal(Marshal(v)) =\n%+v\nwant\n%+v", got, mr)
+	}
+
+	// And the other way around, from a dataset to a value and back to a dataset
+	again, err := Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if !reflect.DeepEqual(again.Elements, ds.Elements) {
+		t.Errorf("Marshal(Unmarshal(ds)) is not the same as ds")
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	var nilMR *dicom2019b.MRImageStorage
+	for _, v := range []interface{}{nilMR, "MR", struct{ Patient dicom2019b.Patient }{}} {
+		if _, err := Marshal(v); err == nil {
+			t.Errorf("Marshal(%T) succeeded, want an error", v)
+		}
+	}
+}
+
+func TestMarshalSOPClassUID(t *testing.T) {
+	mr := testMRImage(t)
+	mr.SOPCommon.SOPClassUID = ""
+
+	ds, err := Marshal(mr)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	e, err := dicom.FindElementByTag(ds.Elements, dicomtag.SOPClassUID)
+	if err != nil || !reflect.DeepEqual(e.Value, []interface{}{"1.2.840.10008.5.1.4.1.1.4"}) {
+		t.Errorf("Marshal SOPClassUID = %v, want the UID of the storage class", e)
+	}
+}
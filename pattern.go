@@ -0,0 +1,203 @@
+package dicom
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// A TagPattern is a tag of the data dictionary that may have "x" digits standing for any hex digit,
+// such as the repeating groups of the overlays (e.g. "(60xx,3000)") or the ranged elements of some
+// retired attributes (e.g. "(0020,31xx)"). The schema uses these patterns as keys of the
+// SchemaDef.TagDefs and in the TagUsage.Path instead of expanding them into every concrete tag.
+//
+// The "x" digits of a group are a repeating group, which only has the even groups from xx00 to xx1E
+// (see PS3.5 section 7.6), so (6000,3000) to (601E,3000) match "(60xx,3000)". The "x" digits of an
+// element match any digit.
+type TagPattern struct {
+	Group, GroupMask     uint16
+	Element, ElementMask uint16
+}
+
+// ParseTagPattern parses a tag pattern in the string form used by the schema (e.g. "(60xx,3000)").
+// The parentheses are optional and the hex digits are case insensitive. A plain tag is a pattern
+// that only matches itself.
+func ParseTagPattern(s string) (TagPattern, error) {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(s), "()"), ",")
+	if len(parts) != 2 {
+		return TagPattern{}, fmt.Errorf("Invalid tag pattern %q", s)
+	}
+
+	p := TagPattern{}
+	var err error
+	p.Group, p.GroupMask, err = parsePatternDigits(parts[0])
+	if err != nil {
+		return TagPattern{}, fmt.Errorf("Invalid tag group in %q: %v", s, err)
+	}
+	p.Element, p.ElementMask, err = parsePatternDigits(parts[1])
+	if err != nil {
+		return TagPattern{}, fmt.Errorf("Invalid tag element in %q: %v", s, err)
+	}
+
+	return p, nil
+}
+
+// Parse four hex digits where an "x" digit is left out of the mask
+func parsePatternDigits(s string) (uint16, uint16, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) != 4 {
+		return 0, 0, fmt.Errorf("Expected 4 digits, not %q", s)
+	}
+
+	mask := uint16(0)
+	for _, c := range s {
+		mask <<= 4
+		if c != 'x' {
+			mask |= 0xf
+		}
+	}
+
+	v, err := strconv.ParseUint(strings.Replace(s, "x", "0", -1), 16, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return uint16(v), mask, nil
+}
+
+// IsRepeating reports whether the pattern has any "x" digits.
+func (p TagPattern) IsRepeating() bool {
+	return p.GroupMask != 0xffff || p.ElementMask != 0xffff
+}
+
+// Match reports whether a concrete tag matches the pattern.
+func (p TagPattern) Match(t dicomtag.Tag) bool {
+	if t.Group&p.GroupMask != p.Group || t.Element&p.ElementMask != p.Element {
+		return false
+	}
+
+	if p.GroupMask != 0xffff {
+		if rg := t.Group &^ p.GroupMask; rg%2 != 0 || rg > 0x1e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The schemas crawled before the repeating groups were kept as patterns have a tag for each of the
+// groups of the curves (50xx) and overlays (60xx) instead, such as (6000,3000) to (60ff,3000)
+func expandedGroup(t dicomtag.Tag) bool {
+	return t.Group&0xff00 == 0x5000 || t.Group&0xff00 == 0x6000
+}
+
+// Tags provides all of the concrete tags that match the pattern in ascending order. Patterns
+// with "x" digits in the element can have thousands of tags (e.g. "(1000,xxx0)").
+func (p TagPattern) Tags() []dicomtag.Tag {
+	groups := []uint16{p.Group}
+	if p.GroupMask != 0xffff {
+		groups = []uint16{}
+		for rg := uint16(0); rg <= 0x1e; rg += 2 {
+			if rg&p.GroupMask == 0 {
+				groups = append(groups, p.Group|rg)
+			}
+		}
+	}
+
+	tags := []dicomtag.Tag{}
+	for _, g := range groups {
+		for re := uint32(0); re <= uint32(^p.ElementMask); re++ {
+			if uint16(re)&p.ElementMask != 0 {
+				continue
+			}
+			tags = append(tags, dicomtag.Tag{Group: g, Element: p.Element | uint16(re)})
+		}
+	}
+
+	return tags
+}
+
+// String provides the pattern in the form used by the schema (e.g. "(60xx,3000)").
+func (p TagPattern) String() string {
+	return "(" + formatPatternDigits(p.Group, p.GroupMask) + "," + formatPatternDigits(p.Element, p.ElementMask) + ")"
+}
+
+func formatPatternDigits(v, mask uint16) string {
+	s := []byte(fmt.Sprintf("%04x", v))
+	for i := range s {
+		if mask&(0xf000>>(4*uint(i))) == 0 {
+			s[i] = 'x'
+		}
+	}
+	return string(s)
+}
+
+// The number of fixed digits, where patterns with more of them are more specific
+func (p TagPattern) fixedDigits() int {
+	n := 0
+	for i := uint(0); i < 16; i += 4 {
+		if p.GroupMask&(0xf<<i) != 0 {
+			n++
+		}
+		if p.ElementMask&(0xf<<i) != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// A tag definition of the schema that is keyed by a pattern
+type patternDef struct {
+	pattern TagPattern
+	key     string
+}
+
+// The tag definitions of a schema with repeating patterns, with the most specific patterns first
+func tagPatterns(schema *SchemaDef) []patternDef {
+	pds := []patternDef{}
+	for key := range schema.TagDefs {
+		if !strings.ContainsAny(key, "xX") {
+			continue
+		}
+		p, err := ParseTagPattern(key)
+		if err != nil {
+			continue
+		}
+		pds = append(pds, patternDef{pattern: p, key: key})
+	}
+
+	sort.Slice(pds, func(i, j int) bool {
+		if ni, nj := pds[i].pattern.fixedDigits(), pds[j].pattern.fixedDigits(); ni != nj {
+			return ni > nj
+		}
+		return pds[i].key < pds[j].key
+	})
+
+	return pds
+}
+
+// Look up the tag definition of a concrete tag, falling back to the repeating patterns
+func lookupTag(schema *SchemaDef, patterns []patternDef, t dicomtag.Tag) (TagDef, bool) {
+	if td, ok := schema.TagDefs[t.String()]; ok {
+		return td, true
+	}
+
+	for _, pd := range patterns {
+		if pd.pattern.Match(t) {
+			return schema.TagDefs[pd.key], true
+		}
+	}
+
+	return TagDef{}, false
+}
+
+// LookupTag looks up the tag definition of a concrete tag (e.g. (6002,3000)) in the schema,
+// including the tag definitions that are keyed by a repeating pattern (e.g. "(60xx,3000)").
+// The most specific pattern is used if more than one of them matches. A SchemaIndex is faster for
+// many lookups since it doesn't need to find the patterns for each of them.
+func (sd *SchemaDef) LookupTag(t dicomtag.Tag) (TagDef, bool) {
+	return lookupTag(sd, tagPatterns(sd), t)
+}
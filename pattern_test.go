@@ -0,0 +1,115 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+func TestTagPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		tag     dicomtag.Tag
+		want    bool
+	}{
+		{"(60xx,3000)", dicomtag.Tag{Group: 0x6000, Element: 0x3000}, true},
+		{"(60xx,3000)", dicomtag.Tag{Group: 0x6002, Element: 0x3000}, true},
+		{"(60xx,3000)", dicomtag.Tag{Group: 0x601e, Element: 0x3000}, true},
+		// Odd groups are private and the repeating groups stop at xx1E
+		{"(60xx,3000)", dicomtag.Tag{Group: 0x6001, Element: 0x3000}, false},
+		{"(60xx,3000)", dicomtag.Tag{Group: 0x6020, Element: 0x3000}, false},
+		{"(60xx,3000)", dicomtag.Tag{Group: 0x6000, Element: 0x3001}, false},
+		{"(60xx,3000)", dicomtag.Tag{Group: 0x5000, Element: 0x3000}, false},
+		{"(50XX,0005)", dicomtag.Tag{Group: 0x5010, Element: 0x0005}, true},
+		// The element digits match any digit
+		{"(0020,31xx)", dicomtag.Tag{Group: 0x0020, Element: 0x31ff}, true},
+		{"(0020,31xx)", dicomtag.Tag{Group: 0x0020, Element: 0x3201}, false},
+		{"(1000,xxx0)", dicomtag.Tag{Group: 0x1000, Element: 0x1230}, true},
+		{"(1000,xxx0)", dicomtag.Tag{Group: 0x1000, Element: 0x1231}, false},
+		// A plain tag only matches itself
+		{"0010,0010", dicomtag.Tag{Group: 0x0010, Element: 0x0010}, true},
+		{"(0010,0010)", dicomtag.Tag{Group: 0x0010, Element: 0x0020}, false},
+	}
+
+	for _, tt := range tests {
+		p, err := ParseTagPattern(tt.pattern)
+		if err != nil {
+			t.Errorf("ParseTagPattern(%q) error: %v", tt.pattern, err)
+			continue
+		}
+		if got := p.Match(tt.tag); got != tt.want {
+			t.Errorf("ParseTagPattern(%q).Match(%v) = %v, want %v", tt.pattern, tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestParseTagPatternInvalid(t *testing.T) {
+	for _, in := range []string{"", "(60xx)", "(60x,3000)", "(60xx,30000)", "(zzzz,0010)"} {
+		if p, err := ParseTagPattern(in); err == nil {
+			t.Errorf("ParseTagPattern(%q) = %v, want an error", in, p)
+		}
+	}
+}
+
+func TestTagPatternTags(t *testing.T) {
+	tests := []struct {
+		pattern string
+		count   int
+		first   dicomtag.Tag
+		last    dicomtag.Tag
+	}{
+		{"(60xx,3000)", 16, dicomtag.Tag{Group: 0x6000, Element: 0x3000}, dicomtag.Tag{Group: 0x601e, Element: 0x3000}},
+		{"(0020,31xx)", 256, dicomtag.Tag{Group: 0x0020, Element: 0x3100}, dicomtag.Tag{Group: 0x0020, Element: 0x31ff}},
+		{"(0010,0010)", 1, dicomtag.Tag{Group: 0x0010, Element: 0x0010}, dicomtag.Tag{Group: 0x0010, Element: 0x0010}},
+	}
+
+	for _, tt := range tests {
+		p := mustParseTagPattern(t, tt.pattern)
+		tags := p.Tags()
+		if len(tags) != tt.count || tags[0] != tt.first || tags[len(tags)-1] != tt.last {
+			t.Errorf("%s.Tags() = %d tags from %v to %v, want %d from %v to %v", tt.pattern, len(tags), tags[0], tags[len(tags)-1], tt.count, tt.first, tt.last)
+		}
+		for _, tag := range tags {
+			if !p.Match(tag) {
+				t.Errorf("%s.Tags() has %v, which doesn't match", tt.pattern, tag)
+			}
+		}
+		if p.String() != tt.pattern {
+			t.Errorf("ParseTagPattern(%q).String() = %q", tt.pattern, p.String())
+		}
+	}
+}
+
+func TestLookupTagPattern(t *testing.T) {
+	schema := &SchemaDef{TagDefs: map[string]TagDef{
+		"(60xx,3000)": {Keyword: "OverlayData"},
+		"(6000,3000)": {Keyword: "FirstOverlayData"},
+		"(0010,0010)": {Keyword: "PatientName"},
+	}}
+	patterns := tagPatterns(schema)
+
+	tests := []struct {
+		tag  dicomtag.Tag
+		want string
+	}{
+		{dicomtag.Tag{Group: 0x6000, Element: 0x3000}, "FirstOverlayData"},
+		{dicomtag.Tag{Group: 0x6004, Element: 0x3000}, "OverlayData"},
+		{dicomtag.Tag{Group: 0x0010, Element: 0x0010}, "PatientName"},
+		{dicomtag.Tag{Group: 0x6005, Element: 0x3000}, ""},
+	}
+
+	for _, tt := range tests {
+		td, ok := lookupTag(schema, patterns, tt.tag)
+		if td.Keyword != tt.want || ok != (tt.want != "") {
+			t.Errorf("lookupTag(%v) = %q, %v, want %q", tt.tag, td.Keyword, ok, tt.want)
+		}
+	}
+}
+
+func mustParseTagPattern(t *testing.T, s string) TagPattern {
+	p, err := ParseTagPattern(s)
+	if err != nil {
+		t.Fatalf("ParseTagPattern(%q) error: %v", s, err)
+	}
+	return p
+}
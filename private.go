@@ -0,0 +1,132 @@
+package dicom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// A PrivateDictionary is the data dictionary of the private tags of one Private Creator (e.g.
+// "SIEMENS CSA HEADER"). Private tags don't have fixed elements, a Private Creator element (gggg,00bb)
+// reserves the block of elements (gggg,bb00) to (gggg,bbFF) of a group for the creator. So the tags of
+// a private dictionary are keyed by their group and the offset in the block, where the block is "xx"
+// (e.g. "(0029,xx10)" for the element (0029,1010) when the creator is in (0029,0010)).
+type PrivateDictionary struct {
+	// The Private Creator, which is the value of the element that reserves the block (e.g. "SIEMENS CSA HEADER")
+	Creator string
+	// Map of private tag (e.g. "(0029,xx10)") to the private tag definitions of the creator. Only the Keyword,
+	// VR and VM of the definitions are expected to be provided.
+	TagDefs map[string]TagDef
+}
+
+var (
+	privateMu           sync.RWMutex
+	privateDictionaries = map[string]*PrivateDictionary{}
+)
+
+// RegisterPrivateDictionary makes a private dictionary available to LookupPrivateTag. The keys of
+// the tag definitions must be a private (odd) group and an element offset (e.g. "(0019,xx0c)"). The
+// dicomprivate sub-package registers the dictionaries of some vendors when imported:
+//
+//	import _ "github.com/macadamian/dicom/dicomprivate"
+//
+// Registering the same creator twice or a dictionary with an invalid key panics.
+func RegisterPrivateDictionary(dict PrivateDictionary) {
+	privateMu.Lock()
+	defer privateMu.Unlock()
+
+	creator := strings.TrimSpace(dict.Creator)
+	if creator == "" {
+		panic("dicom: RegisterPrivateDictionary creator is empty")
+	}
+	if _, dup := privateDictionaries[creator]; dup {
+		panic("dicom: RegisterPrivateDictionary called twice for creator " + creator)
+	}
+
+	pd := &PrivateDictionary{Creator: creator, TagDefs: map[string]TagDef{}}
+	for key, td := range dict.TagDefs {
+		p, err := ParseTagPattern(key)
+		if err != nil || p.Group%2 != 1 || p.GroupMask != 0xffff || p.ElementMask != 0x00ff {
+			panic(fmt.Sprintf("dicom: RegisterPrivateDictionary invalid private tag %q for creator %s", key, creator))
+		}
+		pd.TagDefs[p.String()] = td
+	}
+
+	privateDictionaries[creator] = pd
+}
+
+// PrivateCreators provides the sorted list of the Private Creators that have a registered
+// private dictionary.
+func PrivateCreators() []string {
+	privateMu.RLock()
+	defer privateMu.RUnlock()
+
+	creators := make([]string, 0, len(privateDictionaries))
+	for c := range privateDictionaries {
+		creators = append(creators, c)
+	}
+	sort.Strings(creators)
+
+	return creators
+}
+
+// LookupPrivateTag looks up the definition of a private tag of a dataset (e.g. (0019,100c)) in the
+// registered private dictionaries. The Private Creator is found from the element that reserves the
+// block of the tag in the dataset (e.g. (0019,0010)), so the same tag can have a different
+// definition in another dataset. It provides the Private Creator along with the definition.
+func LookupPrivateTag(ds *dicom.DataSet, t dicomtag.Tag) (string, TagDef, bool) {
+	return lookupPrivateTag(ds.Elements, t)
+}
+
+// Look up a private tag with the Private Creator elements of a dataset or sequence item
+func lookupPrivateTag(elems []*dicom.Element, t dicomtag.Tag) (string, TagDef, bool) {
+	if t.Group%2 != 1 || t.Element < 0x1000 {
+		return "", TagDef{}, false
+	}
+
+	ce, err := dicom.FindElementByTag(elems, dicomtag.Tag{Group: t.Group, Element: t.Element >> 8})
+	if err != nil {
+		return "", TagDef{}, false
+	}
+	creator, err := ce.GetString()
+	if err != nil {
+		return "", TagDef{}, false
+	}
+	creator = strings.TrimSpace(creator)
+
+	privateMu.RLock()
+	pd, ok := privateDictionaries[creator]
+	privateMu.RUnlock()
+	if !ok {
+		return creator, TagDef{}, false
+	}
+
+	td, ok := pd.TagDefs[TagPattern{Group: t.Group, GroupMask: 0xffff, Element: t.Element & 0x00ff, ElementMask: 0x00ff}.String()]
+	return creator, td, ok
+}
+
+// Whether a tag is a Private Creator with a registered private dictionary, or a private tag in one
+func registeredPrivate(elems []*dicom.Element, t dicomtag.Tag) bool {
+	if t.Group%2 == 1 && t.Element >= 0x0010 && t.Element <= 0x00ff {
+		ce, err := dicom.FindElementByTag(elems, t)
+		if err != nil {
+			return false
+		}
+		creator, err := ce.GetString()
+		if err != nil {
+			return false
+		}
+
+		privateMu.RLock()
+		defer privateMu.RUnlock()
+		_, ok := privateDictionaries[strings.TrimSpace(creator)]
+		return ok
+	}
+
+	_, _, ok := lookupPrivateTag(elems, t)
+	return ok
+}
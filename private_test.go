@@ -0,0 +1,104 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+func init() {
+	RegisterPrivateDictionary(PrivateDictionary{
+		Creator: " PRIVATE TEST ",
+		TagDefs: map[string]TagDef{
+			"(0019,XX0C)": {Keyword: "BValue", VR: []string{"IS"}, VM: MustParseVM("1")},
+			"(0019,xx0e)": {Keyword: "DiffusionGradientDirection", VR: []string{"FD"}, VM: MustParseVM("3")},
+		},
+	})
+}
+
+func TestLookupPrivateTag(t *testing.T) {
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		{Tag: dicomtag.Tag{Group: 0x0019, Element: 0x0010}, VR: "LO", Value: []interface{}{"OTHER CREATOR"}},
+		{Tag: dicomtag.Tag{Group: 0x0019, Element: 0x0011}, VR: "LO", Value: []interface{}{"PRIVATE TEST "}},
+	}}
+
+	tests := []struct {
+		tag     dicomtag.Tag
+		creator string
+		keyword string
+	}{
+		// The creator in (0019,0011) reserves the block (0019,1100) to (0019,11ff)
+		{dicomtag.Tag{Group: 0x0019, Element: 0x110c}, "PRIVATE TEST", "BValue"},
+		{dicomtag.Tag{Group: 0x0019, Element: 0x110e}, "PRIVATE TEST", "DiffusionGradientDirection"},
+		{dicomtag.Tag{Group: 0x0019, Element: 0x110d}, "PRIVATE TEST", ""},
+		// The same offset in the block of another creator
+		{dicomtag.Tag{Group: 0x0019, Element: 0x100c}, "OTHER CREATOR", ""},
+		// A block that isn't reserved
+		{dicomtag.Tag{Group: 0x0019, Element: 0x120c}, "", ""},
+		// The Private Creator elements and standard tags aren't in private dictionaries
+		{dicomtag.Tag{Group: 0x0019, Element: 0x0011}, "", ""},
+		{dicomtag.Tag{Group: 0x0018, Element: 0x110c}, "", ""},
+	}
+
+	for _, tt := range tests {
+		creator, td, ok := LookupPrivateTag(ds, tt.tag)
+		if creator != tt.creator || td.Keyword != tt.keyword || ok != (tt.keyword != "") {
+			t.Errorf("LookupPrivateTag(%v) = %q, %q, %v, want %q, %q", tt.tag, creator, td.Keyword, ok, tt.creator, tt.keyword)
+		}
+	}
+}
+
+func TestRegisteredPrivate(t *testing.T) {
+	elems := []*dicom.Element{
+		{Tag: dicomtag.Tag{Group: 0x0019, Element: 0x0010}, VR: "LO", Value: []interface{}{"PRIVATE TEST"}},
+		{Tag: dicomtag.Tag{Group: 0x0021, Element: 0x0010}, VR: "LO", Value: []interface{}{"OTHER CREATOR"}},
+	}
+
+	tests := []struct {
+		tag  dicomtag.Tag
+		want bool
+	}{
+		{dicomtag.Tag{Group: 0x0019, Element: 0x0010}, true},
+		{dicomtag.Tag{Group: 0x0019, Element: 0x100c}, true},
+		{dicomtag.Tag{Group: 0x0019, Element: 0x100d}, false},
+		{dicomtag.Tag{Group: 0x0021, Element: 0x0010}, false},
+		{dicomtag.Tag{Group: 0x0019, Element: 0x0011}, false},
+	}
+
+	for _, tt := range tests {
+		if got := registeredPrivate(elems, tt.tag); got != tt.want {
+			t.Errorf("registeredPrivate(%v) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterPrivateDictionaryPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		dict PrivateDictionary
+	}{
+		{"duplicate creator", PrivateDictionary{Creator: "PRIVATE TEST"}},
+		{"empty creator", PrivateDictionary{Creator: " "}},
+		{"standard group", PrivateDictionary{Creator: "PRIVATE TEST 2", TagDefs: map[string]TagDef{"(0018,xx0c)": {}}}},
+		{"concrete element", PrivateDictionary{Creator: "PRIVATE TEST 3", TagDefs: map[string]TagDef{"(0019,100c)": {}}}},
+		{"invalid tag", PrivateDictionary{Creator: "PRIVATE TEST 4", TagDefs: map[string]TagDef{"(0019)": {}}}},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterPrivateDictionary with a %s did not panic", tt.name)
+				}
+			}()
+			RegisterPrivateDictionary(tt.dict)
+		}()
+	}
+
+	for _, c := range PrivateCreators() {
+		if c != "DEIDENTIFY TEST" && c != "PRIVATE TEST" {
+			t.Errorf("PrivateCreators() = %v, has %q that did not register", PrivateCreators(), c)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package dicom
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// The VRs of the attributes that get a pseudonym instead of an empty or dummy value
+var pseudonymVRs = map[string]bool{"PN": true, "LO": true, "SH": true}
+
+// A PseudonymStore replaces identifying values (e.g. Patient ID) with random pseudonyms and keeps
+// the mapping so that the values can be re-identified later by authorized staff. The mapping is
+// kept on local disk in a file encrypted with AES-GCM, so the file and its key must be kept apart.
+// Use it with DeidentifyOptions for reversible de-identification:
+//
+//	store, err := dicom.OpenPseudonymStore("pseudonyms.bin", key)
+//	err = dicom.Deidentify(ds, schema, dicom.DeidentifyOptions{Pseudonymize: store.Pseudonymize})
+//	err = store.Save()
+//
+// A value always gets the same pseudonym for an attribute, so the pseudonyms of a patient are the
+// same across the studies of a registry. It is safe to use from multiple goroutines.
+type PseudonymStore struct {
+	path string
+	aead cipher.AEAD
+
+	mu sync.Mutex
+	// Map of tag (e.g. "(0010,0020)") to the pseudonyms of the original values
+	pseudonyms map[string]map[string]string
+	// Map of tag to the original values of the pseudonyms
+	originals map[string]map[string]string
+}
+
+// OpenPseudonymStore opens the pseudonym store in an encrypted file, or provides an empty store
+// that will be saved to the file if it doesn't exist yet. The key must be 16, 24 or 32 bytes to
+// use AES-128, AES-192 or AES-256.
+func OpenPseudonymStore(path string, key []byte) (*PseudonymStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid pseudonym store key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PseudonymStore{path: path, aead: aead, pseudonyms: map[string]map[string]string{}, originals: map[string]map[string]string{}}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ns := aead.NonceSize()
+	if len(b) < ns {
+		return nil, fmt.Errorf("Pseudonym store %s is too short to be encrypted", path)
+	}
+	plain, err := aead.Open(nil, b[:ns], b[ns:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt pseudonym store %s, the key may be wrong: %v", path, err)
+	}
+	if err := json.Unmarshal(plain, &s.pseudonyms); err != nil {
+		return nil, fmt.Errorf("Unable to read pseudonym store %s: %v", path, err)
+	}
+
+	for tag, pseudonyms := range s.pseudonyms {
+		s.originals[tag] = map[string]string{}
+		for original, pseudonym := range pseudonyms {
+			s.originals[tag][pseudonym] = original
+		}
+	}
+
+	return s, nil
+}
+
+// Save encrypts the store with a new nonce and writes it to its file, replacing the previous
+// version of the file only once the new version is completely written.
+func (s *PseudonymStore) Save() error {
+	s.mu.Lock()
+	plain, err := json.Marshal(s.pseudonyms)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	b := s.aead.Seal(nonce, nonce, plain, nil)
+
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("Unable to write pseudonym store %s: %v", s.path, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("Unable to write pseudonym store %s: %v", s.path, err)
+	}
+
+	return nil
+}
+
+// Pseudonymize provides the pseudonym of a value of an attribute, adding a new random pseudonym of
+// 16 hexadecimal digits (short enough for any of the string VRs) if the value doesn't have one yet.
+// Empty values stay empty.
+func (s *PseudonymStore) Pseudonymize(t dicomtag.Tag, value string) (string, error) {
+	value = strings.TrimRight(value, "\x00 ")
+	if value == "" {
+		return "", nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag := t.String()
+	if p, ok := s.pseudonyms[tag][value]; ok {
+		return p, nil
+	}
+	if s.pseudonyms[tag] == nil {
+		s.pseudonyms[tag] = map[string]string{}
+		s.originals[tag] = map[string]string{}
+	}
+
+	b := make([]byte, 8)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		p := strings.ToUpper(hex.EncodeToString(b))
+		if _, dup := s.originals[tag][p]; dup {
+			continue
+		}
+		s.pseudonyms[tag][value] = p
+		s.originals[tag][p] = value
+		return p, nil
+	}
+}
+
+// Reidentify provides the original value of a pseudonym of an attribute.
+func (s *PseudonymStore) Reidentify(t dicomtag.Tag, pseudonym string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	original, ok := s.originals[t.String()][strings.TrimRight(pseudonym, "\x00 ")]
+	return original, ok
+}
+
+// ReidentifyDataSet replaces the pseudonyms of a dataset, including those in the items of
+// sequences, with their original values from the store. It provides the number of values that
+// were replaced. The other changes of the de-identification can't be reversed.
+func ReidentifyDataSet(ds *dicom.DataSet, store *PseudonymStore) int {
+	return store.reidentify(ds.Elements)
+}
+
+func (s *PseudonymStore) reidentify(elems []*dicom.Element) int {
+	n := 0
+	for _, e := range elems {
+		if e.VR == "SQ" {
+			for _, item := range e.Value {
+				if ie, ok := item.(*dicom.Element); ok && ie.Tag == dicomtag.Item {
+					n += s.reidentify(itemElements(ie))
+				}
+			}
+			continue
+		}
+
+		for i, v := range e.Value {
+			if p, ok := v.(string); ok {
+				if original, ok := s.Reidentify(e.Tag, p); ok {
+					e.Value[i] = original
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
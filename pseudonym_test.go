@@ -0,0 +1,152 @@
+package dicom
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+var testPseudonymKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestPseudonymStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pseudonym")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pseudonyms.bin")
+
+	store, err := OpenPseudonymStore(path, testPseudonymKey)
+	if err != nil {
+		t.Fatalf("OpenPseudonymStore error: %v", err)
+	}
+
+	tests := []struct {
+		tag   dicomtag.Tag
+		value string
+	}{
+		{dicomtag.PatientID, "12345"},
+		{dicomtag.PatientID, "67890"},
+		{dicomtag.PatientName, "Doe^John"},
+		{dicomtag.AccessionNumber, "A-1"},
+	}
+
+	pseudonyms := map[string]bool{}
+	for _, tt := range tests {
+		p, err := store.Pseudonymize(tt.tag, tt.value)
+		if err != nil {
+			t.Fatalf("Pseudonymize(%v, %q) error: %v", tt.tag, tt.value, err)
+		}
+		if len(p) != 16 || p == tt.value || pseudonyms[p] {
+			t.Errorf("Pseudonymize(%v, %q) = %q, want a new pseudonym of 16 digits", tt.tag, tt.value, p)
+		}
+		pseudonyms[p] = true
+
+		// The same value always gets the same pseudonym
+		if again, _ := store.Pseudonymize(tt.tag, tt.value+" "); again != p {
+			t.Errorf("Pseudonymize(%v, %q) = %q, then %q", tt.tag, tt.value, p, again)
+		}
+	}
+	if p, _ := store.Pseudonymize(dicomtag.PatientID, ""); p != "" {
+		t.Errorf("Pseudonymize of an empty value = %q, want empty", p)
+	}
+
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	loaded, err := OpenPseudonymStore(path, testPseudonymKey)
+	if err != nil {
+		t.Fatalf("OpenPseudonymStore of the saved store error: %v", err)
+	}
+	for _, tt := range tests {
+		p, _ := store.Pseudonymize(tt.tag, tt.value)
+		if got, ok := loaded.Reidentify(tt.tag, p); !ok || got != tt.value {
+			t.Errorf("Reidentify(%v, %q) = %q, %v, want %q", tt.tag, p, got, ok, tt.value)
+		}
+		if again, _ := loaded.Pseudonymize(tt.tag, tt.value); again != p {
+			t.Errorf("Pseudonymize(%v, %q) of the saved store = %q, want %q", tt.tag, tt.value, again, p)
+		}
+	}
+
+	// The pseudonyms are kept by attribute
+	p, _ := store.Pseudonymize(dicomtag.PatientID, "12345")
+	if got, ok := loaded.Reidentify(dicomtag.OtherPatientIDs, p); ok {
+		t.Errorf("Reidentify(%v, %q) = %q, want no original", dicomtag.OtherPatientIDs, p, got)
+	}
+
+	if _, err := OpenPseudonymStore(path, []byte("fedcba9876543210fedcba9876543210")); err == nil {
+		t.Errorf("OpenPseudonymStore with the wrong key succeeded, want an error")
+	}
+	if _, err := OpenPseudonymStore(path, []byte("short")); err == nil {
+		t.Errorf("OpenPseudonymStore with an invalid key succeeded, want an error")
+	}
+}
+
+func TestReidentifyDataSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pseudonym")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenPseudonymStore(filepath.Join(dir, "pseudonyms.bin"), testPseudonymKey)
+	if err != nil {
+		t.Fatalf("OpenPseudonymStore error: %v", err)
+	}
+
+	ds := testDeidentifyDataSet()
+	ds.Elements = append(ds.Elements, &dicom.Element{Tag: dicomtag.OtherPatientIDsSequence, VR: "SQ", Value: []interface{}{
+		&dicom.Element{Tag: dicomtag.Item, VR: "NA", Value: []interface{}{
+			&dicom.Element{Tag: dicomtag.PatientID, VR: "LO", Value: []interface{}{"12345"}},
+		}},
+	}})
+	schema := testDeidentifySchema()
+	schema.TagDefs["(0010,1002)"] = TagDef{Keyword: "OtherPatientIDsSequence", VR: []string{"SQ"}, Deidentify: "K"}
+	schema.ModuleDefs["Test"] = ModuleDef{Tags: append(schema.ModuleDefs["Test"].Tags,
+		TagUsage{Path: []string{"(0010,1002)"}, Type: "3"},
+		TagUsage{Path: []string{"(0010,1002)", "(0010,0020)"}, Type: "1"},
+	)}
+
+	if err := Deidentify(ds, schema, DeidentifyOptions{Pseudonymize: store.Pseudonymize}); err != nil {
+		t.Fatalf("Deidentify error: %v", err)
+	}
+
+	value := func(elems []*dicom.Element, tag dicomtag.Tag) []interface{} {
+		e, err := dicom.FindElementByTag(elems, tag)
+		if err != nil {
+			t.Fatalf("%v is not present", tag)
+		}
+		return e.Value
+	}
+
+	// The identifiers get a pseudonym instead of an empty or dummy value
+	p, _ := store.Pseudonymize(dicomtag.PatientID, "12345")
+	if got := value(ds.Elements, dicomtag.PatientID); !reflect.DeepEqual(got, []interface{}{p}) {
+		t.Errorf("PatientID = %v, want [%s]", got, p)
+	}
+
+	// PatientID, PatientName, StationName, DeviceSerialNumber and the PatientID of the sequence
+	if n := ReidentifyDataSet(ds, store); n != 5 {
+		t.Errorf("ReidentifyDataSet = %d, want 5", n)
+	}
+
+	if got := value(ds.Elements, dicomtag.PatientID); !reflect.DeepEqual(got, []interface{}{"12345"}) {
+		t.Errorf("PatientID = %v, want [12345]", got)
+	}
+	if got := value(ds.Elements, dicomtag.PatientName); !reflect.DeepEqual(got, []interface{}{"Doe^John"}) {
+		t.Errorf("PatientName = %v, want [Doe^John]", got)
+	}
+	seq := value(ds.Elements, dicomtag.OtherPatientIDsSequence)
+	if len(seq) != 1 {
+		t.Fatalf("OtherPatientIDsSequence has %d items, want 1", len(seq))
+	}
+	if got := value(itemElements(seq[0].(*dicom.Element)), dicomtag.PatientID); !reflect.DeepEqual(got, []interface{}{"12345"}) {
+		t.Errorf("PatientID of OtherPatientIDsSequence = %v, want [12345]", got)
+	}
+}
@@ -0,0 +1,125 @@
+package dicom
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// A registered schema keeps the source of a schema version along with the result of
+// loading it so that the (fairly expensive) unmarshaling only happens once.
+type registeredSchema struct {
+	source func() string
+	once   sync.Once
+	schema *SchemaDef
+	index  *SchemaIndex
+	err    error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*registeredSchema{}
+	// The indexes of the loaded schemas by schema
+	indexes = map[*SchemaDef]*SchemaIndex{}
+)
+
+// RegisterSchema makes a version of the schema available to LoadSchema. The source function
+// returns the JSON representation of the SchemaDef and is only invoked the first time that
+// the version is loaded. The dicomYYYYRdata sub-packages register themselves when imported,
+// typically with a blank import:
+//
+//	import _ "github.com/macadamian/dicom/dicom2016bdata"
+//
+// Registering the same version twice panics.
+func RegisterSchema(version string, source func() string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if source == nil {
+		panic("dicom: RegisterSchema source is nil")
+	}
+	if _, dup := registry[version]; dup {
+		panic("dicom: RegisterSchema called twice for version " + version)
+	}
+
+	registry[version] = &registeredSchema{source: source}
+}
+
+// AvailableVersions provides the sorted list of schema versions (e.g. "2014c", "2016b") that
+// have been registered and can be loaded with LoadSchema.
+func AvailableVersions() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	versions := make([]string, 0, len(registry))
+	for v := range registry {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	return versions
+}
+
+// LoadSchema provides the SchemaDef for a registered version of the specification (e.g. "2016b").
+// The schema is unmarshaled the first time it is requested and the same value is returned on
+// subsequent calls, so callers must treat it as read-only. It is safe to call LoadSchema
+// from multiple goroutines.
+func LoadSchema(version string) (*SchemaDef, error) {
+	registryMu.RLock()
+	rs, ok := registry[version]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("Schema version %q is not registered, available versions are %v", version, AvailableVersions())
+	}
+
+	rs.load(version)
+
+	return rs.schema, rs.err
+}
+
+// LoadSchemaIndex is like LoadSchema, but provides an indexed view of the schema. The index
+// is built once and shared between callers.
+func LoadSchemaIndex(version string) (*SchemaIndex, error) {
+	registryMu.RLock()
+	rs, ok := registry[version]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("Schema version %q is not registered, available versions are %v", version, AvailableVersions())
+	}
+
+	rs.load(version)
+
+	return rs.index, rs.err
+}
+
+func (rs *registeredSchema) load(version string) {
+	rs.once.Do(func() {
+		sch := SchemaDef{}
+		if err := json.Unmarshal([]byte(rs.source()), &sch); err != nil {
+			rs.err = fmt.Errorf("Unable to unmarshal schema version %q: %v", version, err)
+			return
+		}
+		rs.schema = &sch
+		rs.index = NewSchemaIndex(&sch)
+
+		registryMu.Lock()
+		indexes[rs.schema] = rs.index
+		registryMu.Unlock()
+	})
+}
+
+// The index of a schema, which is the shared index of the schema if it was provided by LoadSchema
+// or a new index otherwise
+func schemaIndex(schema *SchemaDef) *SchemaIndex {
+	registryMu.RLock()
+	si, ok := indexes[schema]
+	registryMu.RUnlock()
+
+	if ok {
+		return si
+	}
+	return NewSchemaIndex(schema)
+}
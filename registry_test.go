@@ -0,0 +1,121 @@
+package dicom
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const testSchemaStr = `{
+	"ClassDefs": [{"SOPClassUid": "1.2.3.4", "Name": "Test Image Storage", "Modules": [{"Name": "Test", "Usage": "M"}]}],
+	"TagDefs": {"(0010,0020)": {"Keyword": "PatientID", "VR": ["LO"], "VM": "1", "Deidentify": "Z"}},
+	"ModuleDefs": {"Test": {"Tags": [{"Path": ["(0010,0020)"], "Type": "2"}]}}
+}`
+
+// The number of times that the source of the "test-load" version was called
+var testLoadCalls int32
+
+// The versions are registered once, so the tests can run more than once
+func init() {
+	RegisterSchema("test-load", func() string {
+		atomic.AddInt32(&testLoadCalls, 1)
+		return testSchemaStr
+	})
+	RegisterSchema("test-invalid", func() string { return `{"TagDefs": [` })
+	RegisterSchema("test-b", func() string { return testSchemaStr })
+	RegisterSchema("test-a", func() string { return testSchemaStr })
+}
+
+func TestLoadSchema(t *testing.T) {
+	// Concurrent loads unmarshal the schema once and share the result
+	schemas := make([]*SchemaDef, 8)
+	var wg sync.WaitGroup
+	for i := range schemas {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sch, err := LoadSchema("test-load")
+			if err != nil {
+				t.Errorf("LoadSchema error: %v", err)
+			}
+			schemas[i] = sch
+		}(i)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&testLoadCalls); n != 1 {
+		t.Errorf("The source was called %d times, want 1", n)
+	}
+	for _, sch := range schemas {
+		if sch == nil || sch != schemas[0] {
+			t.Fatalf("LoadSchema provided different schemas for the same version")
+		}
+	}
+	if td := schemas[0].TagDefs["(0010,0020)"]; td.Keyword != "PatientID" || td.VM.String() != "1" {
+		t.Errorf("TagDefs[(0010,0020)] = %+v, want PatientID with VM 1", td)
+	}
+
+	si, err := LoadSchemaIndex("test-load")
+	if err != nil {
+		t.Fatalf("LoadSchemaIndex error: %v", err)
+	}
+	if si.SchemaDef != schemas[0] {
+		t.Errorf("LoadSchemaIndex indexes another schema than LoadSchema provided")
+	}
+}
+
+func TestLoadSchemaErrors(t *testing.T) {
+	if _, err := LoadSchema("test-unregistered"); err == nil {
+		t.Errorf("LoadSchema of an unregistered version succeeded, want an error")
+	}
+	if _, err := LoadSchemaIndex("test-unregistered"); err == nil {
+		t.Errorf("LoadSchemaIndex of an unregistered version succeeded, want an error")
+	}
+
+	if _, err := LoadSchema("test-invalid"); err == nil {
+		t.Errorf("LoadSchema of invalid JSON succeeded, want an error")
+	}
+	// The error is kept for the later loads
+	if _, err := LoadSchema("test-invalid"); err == nil {
+		t.Errorf("LoadSchema of invalid JSON succeeded the second time, want an error")
+	}
+}
+
+func TestRegisterSchemaPanics(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		source  func() string
+	}{
+		{"duplicate", "test-a", func() string { return testSchemaStr }},
+		{"nil source", "test-nil", nil},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterSchema with a %s did not panic", tt.name)
+				}
+			}()
+			RegisterSchema(tt.version, tt.source)
+		}()
+	}
+}
+
+func TestAvailableVersions(t *testing.T) {
+	versions := AvailableVersions()
+	index := map[string]int{}
+	for i, v := range versions {
+		index[v] = i
+		if i > 0 && versions[i-1] >= v {
+			t.Errorf("AvailableVersions() = %v, want a sorted list", versions)
+			break
+		}
+	}
+	for _, v := range []string{"test-a", "test-b"} {
+		if _, ok := index[v]; !ok {
+			t.Errorf("AvailableVersions() = %v, want %s", versions, v)
+		}
+	}
+}
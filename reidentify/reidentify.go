@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	gd "github.com/gradienthealth/dicom"
+	"github.com/macadamian/dicom"
+)
+
+// Re-identifies a DICOM file that was de-identified with the pseudonyms of a pseudonym store, or
+// looks up the original value of a single pseudonym, for example:
+//
+//	reidentify -store pseudonyms.bin -keyfile store.key in.dcm out.dcm
+//	reidentify -store pseudonyms.bin -keyfile store.key -lookup "(0010,0020)" 3F2A9C01D4E5B678
+//
+// The key file has the hexadecimal encoding of the AES key of the store.
+func main() {
+	storePath := flag.String("store", "", "The encrypted pseudonym store file")
+	keyPath := flag.String("keyfile", "", "The file with the hexadecimal key of the pseudonym store")
+	lookup := flag.Bool("lookup", false, "Print the original value of a pseudonym of a tag instead of re-identifying a file")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: reidentify -store <file> -keyfile <file> <in.dcm> <out.dcm>\n")
+		fmt.Fprintf(os.Stderr, "       reidentify -store <file> -keyfile <file> -lookup <tag> <pseudonym>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *storePath == "" || *keyPath == "" || flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	keyHex, err := ioutil.ReadFile(*keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(keyHex)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid key file %s: %v\n", *keyPath, err)
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(*storePath); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	store, err := dicom.OpenPseudonymStore(*storePath, key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if *lookup {
+		t, err := dicom.ParseTag(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		original, ok := store.Reidentify(t, flag.Arg(1))
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s is not a pseudonym of %s\n", flag.Arg(1), t)
+			os.Exit(1)
+		}
+		fmt.Println(original)
+		return
+	}
+
+	p, err := gd.NewParserFromFile(flag.Arg(0), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	ds, err := p.Parse(gd.ParseOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to read %s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	n := dicom.ReidentifyDataSet(ds, store)
+
+	if err := gd.WriteDataSetToFile(flag.Arg(1), ds); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to write %s: %v\n", flag.Arg(1), err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Re-identified %d values\n", n)
+}
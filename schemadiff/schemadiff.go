@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/macadamian/dicom"
+	_ "github.com/macadamian/dicom/dicom2013data"
+	_ "github.com/macadamian/dicom/dicom2014adata"
+	_ "github.com/macadamian/dicom/dicom2014bdata"
+	_ "github.com/macadamian/dicom/dicom2014cdata"
+	_ "github.com/macadamian/dicom/dicom2015adata"
+	_ "github.com/macadamian/dicom/dicom2015bdata"
+	_ "github.com/macadamian/dicom/dicom2015cdata"
+	_ "github.com/macadamian/dicom/dicom2016adata"
+	_ "github.com/macadamian/dicom/dicom2016bdata"
+)
+
+// Reports the changes between two versions of the DICOM schema, for example:
+//
+//	schemadiff 2014c 2016b
+//	schemadiff -json 2014c 2016b > changes.json
+func main() {
+	asJSON := flag.Bool("json", false, "Write the changes as JSON instead of text")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: schemadiff [-json] <from-version> <to-version>\n")
+		fmt.Fprintf(os.Stderr, "Available versions: %s\n", strings.Join(dicom.AvailableVersions(), ", "))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	from, err := dicom.LoadSchema(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	to, err := dicom.LoadSchema(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	d := dicom.Diff(from, to)
+
+	if *asJSON {
+		b, err := json.MarshalIndent(d, "", "\t")
+		if err != nil {
+			panic(err)
+		}
+		os.Stdout.Write(b)
+		fmt.Println()
+		return
+	}
+
+	writeText(os.Stdout, flag.Arg(0), flag.Arg(1), d)
+}
+
+func writeText(out io.Writer, from, to string, d dicom.SchemaDiff) {
+	fmt.Fprintf(out, "Changes from %s to %s\n", from, to)
+
+	if d.Empty() {
+		fmt.Fprintf(out, "\nNo changes\n")
+		return
+	}
+
+	if len(d.AddedClasses) > 0 || len(d.RemovedClasses) > 0 || len(d.ChangedClasses) > 0 {
+		fmt.Fprintf(out, "\nSOP Classes\n")
+	}
+	for _, cd := range d.AddedClasses {
+		fmt.Fprintf(out, "  + %s %s\n", cd.SOPClassUid, cd.Name)
+	}
+	for _, cd := range d.RemovedClasses {
+		fmt.Fprintf(out, "  - %s %s\n", cd.SOPClassUid, cd.Name)
+	}
+	for _, cd := range d.ChangedClasses {
+		fmt.Fprintf(out, "  * %s %s\n", cd.SOPClassUid, cd.Name)
+		for _, mu := range cd.AddedModules {
+			fmt.Fprintf(out, "      + module %s (%s)\n", mu.Name, mu.Usage)
+		}
+		for _, mu := range cd.RemovedModules {
+			fmt.Fprintf(out, "      - module %s (%s)\n", mu.Name, mu.Usage)
+		}
+		for _, mc := range cd.ChangedModules {
+			fmt.Fprintf(out, "      * module %s usage %s -> %s\n", mc.Name, mc.From, mc.To)
+		}
+	}
+
+	if len(d.ChangedModules) > 0 {
+		fmt.Fprintf(out, "\nModules\n")
+	}
+	for _, md := range d.ChangedModules {
+		fmt.Fprintf(out, "  * %s\n", md.Name)
+		for _, tu := range md.AddedTags {
+			fmt.Fprintf(out, "      + %s type %s\n", strings.Join(tu.Path, ">"), strings.TrimSpace(tu.Type))
+		}
+		for _, tu := range md.RemovedTags {
+			fmt.Fprintf(out, "      - %s type %s\n", strings.Join(tu.Path, ">"), strings.TrimSpace(tu.Type))
+		}
+		for _, tc := range md.RetypedTags {
+			fmt.Fprintf(out, "      * %s type %s -> %s\n", strings.Join(tc.Path, ">"), tc.From, tc.To)
+		}
+	}
+
+	if len(d.AddedTags) > 0 || len(d.RemovedTags) > 0 || len(d.ChangedTags) > 0 {
+		fmt.Fprintf(out, "\nTags\n")
+	}
+	for _, tc := range d.AddedTags {
+		fmt.Fprintf(out, "  + %s %s %s %s\n", tc.Tag, tc.To.Keyword, strings.Join(tc.To.VR, " or "), tc.To.VM)
+	}
+	for _, tc := range d.RemovedTags {
+		fmt.Fprintf(out, "  - %s %s %s %s\n", tc.Tag, tc.From.Keyword, strings.Join(tc.From.VR, " or "), tc.From.VM)
+	}
+	for _, tc := range d.ChangedTags {
+		fmt.Fprintf(out, "  * %s %s\n", tc.Tag, tc.To.Keyword)
+		for _, f := range tc.Fields {
+			switch f {
+			case "Keyword":
+				fmt.Fprintf(out, "      Keyword %s -> %s\n", tc.From.Keyword, tc.To.Keyword)
+			case "VR":
+				fmt.Fprintf(out, "      VR %s -> %s\n", strings.Join(tc.From.VR, " or "), strings.Join(tc.To.VR, " or "))
+			case "VM":
+				fmt.Fprintf(out, "      VM %s -> %s\n", tc.From.VM, tc.To.VM)
+			case "Deidentify":
+				fmt.Fprintf(out, "      Deidentify %q -> %q\n", tc.From.Deidentify, tc.To.Deidentify)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/macadamian/dicom"
+)
+
+func TestWriteText(t *testing.T) {
+	d := dicom.SchemaDiff{
+		AddedClasses: []dicom.ClassDef{{SOPClassUid: "1.2.3.3", Name: "Added Image Storage"}},
+		ChangedClasses: []dicom.ClassDiff{{
+			SOPClassUid:    "1.2.3.1",
+			Name:           "Kept Image Storage",
+			ChangedModules: []dicom.ModuleUsageChange{{Name: "Image", From: "U", To: "M"}},
+		}},
+		ChangedModules: []dicom.ModuleDiff{{
+			Name:        "Patient",
+			AddedTags:   []dicom.TagUsage{{Path: []string{"(0010,1002)", "(0010,0020)"}, Type: "1 "}},
+			RetypedTags: []dicom.TagUsageChange{{Path: []string{"(0010,0020)"}, From: "2", To: "1"}},
+		}},
+		RemovedTags: []dicom.TagDefChange{{Tag: "(0028,0106)", From: dicom.TagDef{Keyword: "SmallestImagePixelValue", VR: []string{"US", "SS"}, VM: dicom.MustParseVM("1")}}},
+		ChangedTags: []dicom.TagDefChange{{
+			Tag:    "(0010,0030)",
+			From:   dicom.TagDef{Keyword: "PatientBirthDate", VM: dicom.MustParseVM("1")},
+			To:     dicom.TagDef{Keyword: "PatientBirthDate", VM: dicom.MustParseVM("1-n")},
+			Fields: []string{"VM"},
+		}},
+	}
+
+	want := `Changes from a to b
+
+SOP Classes
+  + 1.2.3.3 Added Image Storage
+  * 1.2.3.1 Kept Image Storage
+      * module Image usage U -> M
+
+Modules
+  * Patient
+      + (0010,1002)>(0010,0020) type 1
+      * (0010,0020) type 2 -> 1
+
+Tags
+  - (0028,0106) SmallestImagePixelValue US or SS 1
+  * (0010,0030) PatientBirthDate
+      VM 1 -> 1-n
+`
+
+	var b bytes.Buffer
+	writeText(&b, "a", "b", d)
+	if b.String() != want {
+		t.Errorf("writeText =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	writeText(&b, "a", "a", dicom.SchemaDiff{})
+	if want := "Changes from a to a\n\nNo changes\n"; b.String() != want {
+		t.Errorf("writeText of no changes =\n%s\nwant\n%s", b.String(), want)
+	}
+}
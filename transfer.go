@@ -0,0 +1,246 @@
+package dicom
+
+import (
+	"strings"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// A TransferSyntax describes how a dataset is encoded with a Transfer Syntax UID (see PS3.5 Section 10
+// and Annex A).
+type TransferSyntax struct {
+	UID  string
+	Name string
+	// BigEndian is true for the (retired) big endian byte ordering, all other transfer syntaxes are little endian.
+	BigEndian bool
+	// ExplicitVR is true if the VR of each element is encoded, false for the implicit VR transfer syntaxes.
+	ExplicitVR bool
+	// Deflated is true if the dataset is compressed as a whole with the deflate algorithm.
+	Deflated bool
+	// Encapsulated is true if the Pixel Data is compressed into fragments instead of being native.
+	Encapsulated bool
+	// Lossy is true if the pixel data was compressed with a loss of information.
+	Lossy bool
+	// MaybeLossy is true if the pixel data may have been compressed with or without a loss of information
+	// (e.g. JPEG 2000), which can't be told from the transfer syntax alone.
+	MaybeLossy bool
+	// Video is true for the video compression (MPEG-2, MPEG-4 and HEVC) transfer syntaxes.
+	Video bool
+	// Retired is true for transfer syntaxes that have been retired from the specification.
+	Retired bool
+}
+
+// The encodings of the transfer syntaxes of the UID registry, which only has their names and whether
+// they are retired
+var transferEncodings = func() map[string]TransferSyntax {
+	native := TransferSyntax{ExplicitVR: true}
+	bigEndian := TransferSyntax{ExplicitVR: true, BigEndian: true}
+	deflated := TransferSyntax{ExplicitVR: true, Deflated: true}
+	lossless := TransferSyntax{ExplicitVR: true, Encapsulated: true}
+	lossy := TransferSyntax{ExplicitVR: true, Encapsulated: true, Lossy: true}
+	maybeLossy := TransferSyntax{ExplicitVR: true, Encapsulated: true, MaybeLossy: true}
+	video := TransferSyntax{ExplicitVR: true, Encapsulated: true, Lossy: true, Video: true}
+
+	return map[string]TransferSyntax{
+		"1.2.840.10008.1.2":       {},         // Implicit VR Little Endian
+		"1.2.840.10008.1.2.1":     native,     // Explicit VR Little Endian
+		"1.2.840.10008.1.2.1.99":  deflated,   // Deflated Explicit VR Little Endian
+		"1.2.840.10008.1.2.2":     bigEndian,  // Explicit VR Big Endian
+		"1.2.840.10008.1.2.4.50":  lossy,      // JPEG Baseline (Process 1)
+		"1.2.840.10008.1.2.4.51":  lossy,      // JPEG Extended (Process 2 & 4)
+		"1.2.840.10008.1.2.4.52":  lossy,      // JPEG Extended (Process 3 & 5)
+		"1.2.840.10008.1.2.4.53":  lossy,      // JPEG Spectral Selection, Non-Hierarchical (Process 6 & 8)
+		"1.2.840.10008.1.2.4.54":  lossy,      // JPEG Spectral Selection, Non-Hierarchical (Process 7 & 9)
+		"1.2.840.10008.1.2.4.55":  lossy,      // JPEG Full Progression, Non-Hierarchical (Process 10 & 12)
+		"1.2.840.10008.1.2.4.56":  lossy,      // JPEG Full Progression, Non-Hierarchical (Process 11 & 13)
+		"1.2.840.10008.1.2.4.57":  lossless,   // JPEG Lossless, Non-Hierarchical (Process 14)
+		"1.2.840.10008.1.2.4.58":  lossless,   // JPEG Lossless, Non-Hierarchical (Process 15)
+		"1.2.840.10008.1.2.4.59":  lossy,      // JPEG Extended, Hierarchical (Process 16 & 18)
+		"1.2.840.10008.1.2.4.60":  lossy,      // JPEG Extended, Hierarchical (Process 17 & 19)
+		"1.2.840.10008.1.2.4.61":  lossy,      // JPEG Spectral Selection, Hierarchical (Process 20 & 22)
+		"1.2.840.10008.1.2.4.62":  lossy,      // JPEG Spectral Selection, Hierarchical (Process 21 & 23)
+		"1.2.840.10008.1.2.4.63":  lossy,      // JPEG Full Progression, Hierarchical (Process 24 & 26)
+		"1.2.840.10008.1.2.4.64":  lossy,      // JPEG Full Progression, Hierarchical (Process 25 & 27)
+		"1.2.840.10008.1.2.4.65":  lossless,   // JPEG Lossless, Hierarchical (Process 28)
+		"1.2.840.10008.1.2.4.66":  lossless,   // JPEG Lossless, Hierarchical (Process 29)
+		"1.2.840.10008.1.2.4.70":  lossless,   // JPEG Lossless, Non-Hierarchical, First-Order Prediction (Process 14 [Selection Value 1])
+		"1.2.840.10008.1.2.4.80":  lossless,   // JPEG-LS Lossless Image Compression
+		"1.2.840.10008.1.2.4.81":  maybeLossy, // JPEG-LS Lossy (Near-Lossless) Image Compression
+		"1.2.840.10008.1.2.4.90":  lossless,   // JPEG 2000 Image Compression (Lossless Only)
+		"1.2.840.10008.1.2.4.91":  maybeLossy, // JPEG 2000 Image Compression
+		"1.2.840.10008.1.2.4.92":  lossless,   // JPEG 2000 Part 2 Multi-component Image Compression (Lossless Only)
+		"1.2.840.10008.1.2.4.93":  maybeLossy, // JPEG 2000 Part 2 Multi-component Image Compression
+		"1.2.840.10008.1.2.4.94":  native,     // JPIP Referenced
+		"1.2.840.10008.1.2.4.95":  deflated,   // JPIP Referenced Deflate
+		"1.2.840.10008.1.2.4.100": video,      // MPEG2 Main Profile / Main Level
+		"1.2.840.10008.1.2.4.101": video,      // MPEG2 Main Profile / High Level
+		"1.2.840.10008.1.2.4.102": video,      // MPEG-4 AVC/H.264 High Profile / Level 4.1
+		"1.2.840.10008.1.2.4.103": video,      // MPEG-4 AVC/H.264 BD-compatible High Profile / Level 4.1
+		"1.2.840.10008.1.2.4.104": video,      // MPEG-4 AVC/H.264 High Profile / Level 4.2 For 2D Video
+		"1.2.840.10008.1.2.4.105": video,      // MPEG-4 AVC/H.264 High Profile / Level 4.2 For 3D Video
+		"1.2.840.10008.1.2.4.106": video,      // MPEG-4 AVC/H.264 Stereo High Profile / Level 4.2
+		"1.2.840.10008.1.2.4.107": video,      // HEVC/H.265 Main Profile / Level 5.1
+		"1.2.840.10008.1.2.4.108": video,      // HEVC/H.265 Main 10 Profile / Level 5.1
+		"1.2.840.10008.1.2.5":     lossless,   // RLE Lossless
+		"1.2.840.10008.1.2.6.1":   native,     // RFC 2557 MIME encapsulation
+		"1.2.840.10008.1.2.6.2":   native,     // XML Encoding
+		"1.2.840.10008.1.20":      {},         // Papyrus 3 Implicit VR Little Endian
+	}
+}()
+
+// LookupTransferSyntax provides the encoding of a Transfer Syntax UID (e.g. "1.2.840.10008.1.2.4.50")
+// with its name and retirement from the UID registry built into the github.com/gradienthealth/dicom/dicomuid
+// package. See SchemaDef.LookupTransferSyntax for the UID registry of a version of the schema.
+func LookupTransferSyntax(uid string) (TransferSyntax, bool) {
+	return lookupTransferSyntax(lookupBuiltinUID, uid)
+}
+
+// LookupTransferSyntax provides the encoding of a Transfer Syntax UID with its name and retirement from
+// the UID registry of this version of the schema, see SchemaDef.LookupUID. The UID must be a transfer
+// syntax of the registry whose encoding is known.
+func (sd *SchemaDef) LookupTransferSyntax(uid string) (TransferSyntax, bool) {
+	return lookupTransferSyntax(sd.LookupUID, uid)
+}
+
+// Look up the encoding of a transfer syntax along with its name and retirement from a UID registry
+func lookupTransferSyntax(lookupUID func(string) (UIDDef, bool), uid string) (TransferSyntax, bool) {
+	uid = strings.TrimRight(uid, "\x00 ")
+
+	ud, ok := lookupUID(uid)
+	if !ok || ud.Type != UIDTypeTransferSyntax {
+		return TransferSyntax{}, false
+	}
+	ts, ok := transferEncodings[uid]
+	if !ok {
+		return TransferSyntax{}, false
+	}
+
+	ts.UID, ts.Name, ts.Retired = uid, ud.Name, ud.Retired
+	return ts, true
+}
+
+// TransferSyntaxRules configures the checks of CheckTransferSyntax that depend on the policies of the
+// application, such as an archive that doesn't accept lossy compression for some SOP Classes.
+type TransferSyntaxRules struct {
+	// LossyAllowed decides whether a lossy transfer syntax is allowed for a SOP Class UID. Lossy
+	// transfer syntaxes are allowed for all SOP Classes if it is nil. See RejectLossy.
+	LossyAllowed func(sopClassUID string) bool
+	// RetiredAllowed is true if retired transfer syntaxes are accepted without a warning.
+	RetiredAllowed bool
+}
+
+// RejectLossy provides a TransferSyntaxRules.LossyAllowed that rejects lossy transfer syntaxes for
+// the provided SOP Class UIDs and allows them for any other SOP Class.
+func RejectLossy(sopClassUIDs ...string) func(string) bool {
+	rejected := map[string]bool{}
+	for _, uid := range sopClassUIDs {
+		rejected[uid] = true
+	}
+	return func(sopClassUID string) bool {
+		return !rejected[sopClassUID]
+	}
+}
+
+// CheckTransferSyntax checks the Transfer Syntax UID (0002,0010) of the file meta information of a
+// dataset against its SOP Class and pixel data:
+//
+//	The Transfer Syntax UID must be present and be a known transfer syntax.
+//	Retired transfer syntaxes are a warning, unless the rules allow them.
+//	Video transfer syntaxes are only for the Video Endoscopic, Microscopic and Photographic Image Storage SOP Classes.
+//	Pixel Data must be encapsulated with an encapsulated transfer syntax and native otherwise.
+//	Lossy transfer syntaxes must be allowed for the SOP Class by the rules.
+//	Lossy Image Compression (0028,2110) must be "01" for Pixel Data with a lossy transfer syntax.
+//
+// The transfer syntaxes that may be lossy, such as JPEG 2000 which can also be reversible, are
+// warnings when they aren't allowed by the rules and when Lossy Image Compression is not present to
+// tell whether the compression was lossy.
+//
+// The issues are reported in a ValidationReport like those of Validate.
+func CheckTransferSyntax(ds *dicom.DataSet, rules TransferSyntaxRules) ValidationReport {
+	report := ValidationReport{}
+	tsPath := []string{dicomtag.TransferSyntaxUID.String()}
+
+	if sce, err := ds.FindElementByTag(dicomtag.SOPClassUID); err == nil {
+		report.SOPClassUID, _ = sce.GetString()
+		report.SOPClassUID = strings.TrimRight(report.SOPClassUID, "\x00 ")
+	}
+	if ud, ok := lookupBuiltinUID(report.SOPClassUID); ok {
+		report.ClassName = ud.Name
+	}
+
+	tse, err := ds.FindElementByTag(dicomtag.TransferSyntaxUID)
+	if err != nil {
+		report.add(SeverityError, IssueInvalidTransferSyntax, "", tsPath, "TransferSyntaxUID is not present")
+		return report
+	}
+	uid, _ := tse.GetString()
+
+	ts, ok := LookupTransferSyntax(uid)
+	if !ok {
+		report.add(SeverityError, IssueInvalidTransferSyntax, "", tsPath, "%q is not a known transfer syntax", uid)
+		return report
+	}
+
+	if ts.Retired && !rules.RetiredAllowed {
+		report.add(SeverityWarning, IssueRetiredTransferSyntax, "", tsPath, "%s is retired", ts.Name)
+	}
+
+	if ts.Video && !videoClasses[report.SOPClassUID] {
+		report.add(SeverityError, IssueInvalidTransferSyntax, "", tsPath, "%s is only for the Video SOP Classes, not %s", ts.Name, className(report))
+	}
+
+	pde, err := ds.FindElementByTag(dicomtag.PixelData)
+	hasPixelData := err == nil
+	if hasPixelData && len(pde.Value) == 1 {
+		if pdi, ok := pde.Value[0].(dicom.PixelDataInfo); ok && pdi.IsEncapsulated != ts.Encapsulated {
+			if ts.Encapsulated {
+				report.add(SeverityError, IssuePixelEncoding, "", []string{dicomtag.PixelData.String()}, "Pixel Data is native, but %s is encapsulated", ts.Name)
+			} else {
+				report.add(SeverityError, IssuePixelEncoding, "", []string{dicomtag.PixelData.String()}, "Pixel Data is encapsulated, but %s is native", ts.Name)
+			}
+		}
+	}
+
+	if rules.LossyAllowed != nil && !rules.LossyAllowed(report.SOPClassUID) {
+		if ts.Lossy {
+			report.add(SeverityError, IssueLossyTransferSyntax, "", tsPath, "Lossy %s is not allowed for %s", ts.Name, className(report))
+		} else if ts.MaybeLossy {
+			report.add(SeverityWarning, IssueLossyTransferSyntax, "", tsPath, "%s may be lossy, which is not allowed for %s", ts.Name, className(report))
+		}
+	}
+
+	if !hasPixelData {
+		return report
+	}
+
+	lossyPath := []string{dicomtag.LossyImageCompression.String()}
+	lce, err := ds.FindElementByTag(dicomtag.LossyImageCompression)
+	switch {
+	case ts.Lossy && err != nil:
+		report.add(SeverityError, IssuePixelEncoding, "", lossyPath, "LossyImageCompression is not present with lossy %s", ts.Name)
+	case ts.Lossy:
+		if s, _ := lce.GetString(); strings.TrimSpace(s) != "01" {
+			report.add(SeverityError, IssuePixelEncoding, "", lossyPath, "LossyImageCompression is %q, expected \"01\" with lossy %s", s, ts.Name)
+		}
+	case ts.MaybeLossy && err != nil:
+		report.add(SeverityWarning, IssuePixelEncoding, "", lossyPath, "LossyImageCompression is not present to tell whether %s is lossy", ts.Name)
+	}
+
+	return report
+}
+
+// The SOP Classes of the video transfer syntaxes (see PS3.5 Section 8.2.5)
+var videoClasses = map[string]bool{
+	"1.2.840.10008.5.1.4.1.1.77.1.1.1": true, // Video Endoscopic Image Storage
+	"1.2.840.10008.5.1.4.1.1.77.1.2.1": true, // Video Microscopic Image Storage
+	"1.2.840.10008.5.1.4.1.1.77.1.4.1": true, // Video Photographic Image Storage
+}
+
+// The name of the SOP Class of a report for messages, or its UID if the name isn't known
+func className(report ValidationReport) string {
+	if report.ClassName != "" {
+		return report.ClassName
+	}
+	return report.SOPClassUID
+}
@@ -0,0 +1,129 @@
+package dicom
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+func TestLookupTransferSyntax(t *testing.T) {
+	tests := []struct {
+		uid  string
+		want TransferSyntax
+	}{
+		{"1.2.840.10008.1.2", TransferSyntax{UID: "1.2.840.10008.1.2", Name: "Implicit VR Little Endian"}},
+		{"1.2.840.10008.1.2.1\x00", TransferSyntax{UID: "1.2.840.10008.1.2.1", Name: "Explicit VR Little Endian", ExplicitVR: true}},
+		{"1.2.840.10008.1.2.2", TransferSyntax{UID: "1.2.840.10008.1.2.2", Name: "Explicit VR Big Endian", ExplicitVR: true, BigEndian: true, Retired: true}},
+		{"1.2.840.10008.1.2.4.50 ", TransferSyntax{UID: "1.2.840.10008.1.2.4.50", Name: "JPEG Baseline (Process 1)", ExplicitVR: true, Encapsulated: true, Lossy: true}},
+		{"1.2.840.10008.1.2.4.91", TransferSyntax{UID: "1.2.840.10008.1.2.4.91", Name: "JPEG 2000 Image Compression", ExplicitVR: true, Encapsulated: true, MaybeLossy: true}},
+		{"1.2.840.10008.1.2.4.102", TransferSyntax{UID: "1.2.840.10008.1.2.4.102", Name: "MPEG-4 AVC/H.264 High Profile / Level 4.1", ExplicitVR: true, Encapsulated: true, Lossy: true, Video: true}},
+		// A SOP Class and a UID that isn't in the registry
+		{"1.2.840.10008.5.1.4.1.1.4", TransferSyntax{}},
+		{"1.2.3.4", TransferSyntax{}},
+	}
+
+	for _, tt := range tests {
+		ts, ok := LookupTransferSyntax(tt.uid)
+		if ts != tt.want || ok != (tt.want.UID != "") {
+			t.Errorf("LookupTransferSyntax(%q) = %+v, %v, want %+v", tt.uid, ts, ok, tt.want)
+		}
+	}
+}
+
+func TestSchemaLookupTransferSyntax(t *testing.T) {
+	schema := &SchemaDef{UIDs: map[string]UIDDef{
+		"1.2.840.10008.1.2.1":       {Name: "Explicit VR Little Endian", Keyword: "ExplicitVRLittleEndian", Type: UIDTypeTransferSyntax},
+		"1.2.840.10008.1.2.5":       {Name: "RLE Lossless", Keyword: "RLELossless", Type: UIDTypeTransferSyntax, Retired: true},
+		"1.2.840.10008.1.2.4.201":   {Name: "High-Throughput JPEG 2000 Image Compression (Lossless Only)", Type: UIDTypeTransferSyntax},
+		"1.2.840.10008.5.1.4.1.1.4": {Name: "MR Image Storage", Keyword: "MRImageStorage", Type: UIDTypeSOPClass},
+	}}
+
+	tests := []struct {
+		uid  string
+		want TransferSyntax
+	}{
+		{"1.2.840.10008.1.2.1", TransferSyntax{UID: "1.2.840.10008.1.2.1", Name: "Explicit VR Little Endian", ExplicitVR: true}},
+		// The retirement is from the registry of the schema
+		{"1.2.840.10008.1.2.5", TransferSyntax{UID: "1.2.840.10008.1.2.5", Name: "RLE Lossless", ExplicitVR: true, Encapsulated: true, Retired: true}},
+		// A transfer syntax with an unknown encoding, a SOP Class and a transfer syntax that isn't in the registry of the schema
+		{"1.2.840.10008.1.2.4.201", TransferSyntax{}},
+		{"1.2.840.10008.5.1.4.1.1.4", TransferSyntax{}},
+		{"1.2.840.10008.1.2", TransferSyntax{}},
+	}
+
+	for _, tt := range tests {
+		ts, ok := schema.LookupTransferSyntax(tt.uid)
+		if ts != tt.want || ok != (tt.want.UID != "") {
+			t.Errorf("LookupTransferSyntax(%q) = %+v, %v, want %+v", tt.uid, ts, ok, tt.want)
+		}
+	}
+}
+
+func TestCheckTransferSyntax(t *testing.T) {
+	const (
+		mrImage = "1.2.840.10008.5.1.4.1.1.4"
+		video   = "1.2.840.10008.5.1.4.1.1.77.1.1.1"
+	)
+	el := func(tag dicomtag.Tag, vr string, values ...interface{}) *dicom.Element {
+		return &dicom.Element{Tag: tag, VR: vr, Value: values}
+	}
+	pixelData := func(encapsulated bool) *dicom.Element {
+		return el(dicomtag.PixelData, "OB", dicom.PixelDataInfo{IsEncapsulated: encapsulated})
+	}
+	lossy := el(dicomtag.LossyImageCompression, "CS", "01")
+	noLossy := TransferSyntaxRules{LossyAllowed: RejectLossy(mrImage)}
+
+	tests := []struct {
+		name  string
+		class string
+		ts    string
+		elems []*dicom.Element
+		rules TransferSyntaxRules
+		want  []IssueKind
+	}{
+		{"native", mrImage, "1.2.840.10008.1.2.1", []*dicom.Element{pixelData(false)}, noLossy, nil},
+		{"lossy", mrImage, "1.2.840.10008.1.2.4.50", []*dicom.Element{pixelData(true), lossy}, TransferSyntaxRules{}, nil},
+		{"no pixel data", mrImage, "1.2.840.10008.1.2.4.50", nil, TransferSyntaxRules{}, nil},
+		{"missing", mrImage, "", nil, TransferSyntaxRules{}, []IssueKind{IssueInvalidTransferSyntax}},
+		{"unknown", mrImage, "1.2.3.4", nil, TransferSyntaxRules{}, []IssueKind{IssueInvalidTransferSyntax}},
+		{"retired", mrImage, "1.2.840.10008.1.2.2", []*dicom.Element{pixelData(false)}, TransferSyntaxRules{}, []IssueKind{IssueRetiredTransferSyntax}},
+		{"retired allowed", mrImage, "1.2.840.10008.1.2.2", []*dicom.Element{pixelData(false)}, TransferSyntaxRules{RetiredAllowed: true}, nil},
+		{"video", video, "1.2.840.10008.1.2.4.100", []*dicom.Element{pixelData(true), lossy}, TransferSyntaxRules{}, nil},
+		{"video of another class", mrImage, "1.2.840.10008.1.2.4.100", []*dicom.Element{pixelData(true), lossy}, TransferSyntaxRules{}, []IssueKind{IssueInvalidTransferSyntax}},
+		{"native pixel data", mrImage, "1.2.840.10008.1.2.5", []*dicom.Element{pixelData(false)}, TransferSyntaxRules{}, []IssueKind{IssuePixelEncoding}},
+		{"encapsulated pixel data", mrImage, "1.2.840.10008.1.2", []*dicom.Element{pixelData(true)}, TransferSyntaxRules{}, []IssueKind{IssuePixelEncoding}},
+		{"lossy rejected", mrImage, "1.2.840.10008.1.2.4.50", []*dicom.Element{pixelData(true), lossy}, noLossy, []IssueKind{IssueLossyTransferSyntax}},
+		{"lossy of another class", video, "1.2.840.10008.1.2.4.100", []*dicom.Element{pixelData(true), lossy}, noLossy, nil},
+		{"maybe lossy rejected", mrImage, "1.2.840.10008.1.2.4.91", []*dicom.Element{pixelData(true), lossy}, noLossy, []IssueKind{IssueLossyTransferSyntax}},
+		{"lossless rejecting lossy", mrImage, "1.2.840.10008.1.2.4.90", []*dicom.Element{pixelData(true)}, noLossy, nil},
+		{"lossy without LossyImageCompression", mrImage, "1.2.840.10008.1.2.4.50", []*dicom.Element{pixelData(true)}, TransferSyntaxRules{}, []IssueKind{IssuePixelEncoding}},
+		{"lossy with LossyImageCompression 00", mrImage, "1.2.840.10008.1.2.4.50", []*dicom.Element{pixelData(true), el(dicomtag.LossyImageCompression, "CS", "00")}, TransferSyntaxRules{}, []IssueKind{IssuePixelEncoding}},
+		{"maybe lossy without LossyImageCompression", mrImage, "1.2.840.10008.1.2.4.91", []*dicom.Element{pixelData(true)}, TransferSyntaxRules{}, []IssueKind{IssuePixelEncoding}},
+	}
+
+	for _, tt := range tests {
+		elems := []*dicom.Element{el(dicomtag.SOPClassUID, "UI", tt.class)}
+		if tt.ts != "" {
+			elems = append(elems, el(dicomtag.TransferSyntaxUID, "UI", tt.ts))
+		}
+		elems = append(elems, tt.elems...)
+
+		report := CheckTransferSyntax(&dicom.DataSet{Elements: elems}, tt.rules)
+
+		kinds := []IssueKind{}
+		for _, vi := range report.Issues {
+			kinds = append(kinds, vi.Kind)
+		}
+		if len(tt.want) == 0 {
+			tt.want = []IssueKind{}
+		}
+		if !reflect.DeepEqual(kinds, tt.want) {
+			t.Errorf("%s: CheckTransferSyntax issues = %v, want %v", tt.name, report.Issues, tt.want)
+		}
+		if report.SOPClassUID != tt.class {
+			t.Errorf("%s: CheckTransferSyntax SOPClassUID = %q, want %q", tt.name, report.SOPClassUID, tt.class)
+		}
+	}
+}
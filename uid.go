@@ -0,0 +1,60 @@
+package dicom
+
+import (
+	"strings"
+
+	"github.com/gradienthealth/dicom/dicomuid"
+)
+
+// A UIDType is the type of a UID in the UID registry of PS3.6 (e.g. "Transfer Syntax").
+type UIDType string
+
+const (
+	UIDTypeTransferSyntax            UIDType = "Transfer Syntax"
+	UIDTypeSOPClass                  UIDType = "SOP Class"
+	UIDTypeMetaSOPClass              UIDType = "Meta SOP Class"
+	UIDTypeServiceClass              UIDType = "Service Class"
+	UIDTypeWellKnownSOPInstance      UIDType = "Well-known SOP Instance"
+	UIDTypeWellKnownFrameOfReference UIDType = "Well-known frame of reference"
+	UIDTypeCodingScheme              UIDType = "Coding Scheme"
+	UIDTypeApplicationContextName    UIDType = "Application Context Name"
+)
+
+var uidTypes = []UIDType{UIDTypeTransferSyntax, UIDTypeSOPClass, UIDTypeMetaSOPClass, UIDTypeServiceClass, UIDTypeWellKnownSOPInstance,
+	UIDTypeWellKnownFrameOfReference, UIDTypeCodingScheme, UIDTypeApplicationContextName}
+
+// LookupUID looks up a UID (e.g. "1.2.840.10008.1.2.1") in the UID registry of this version of the
+// schema, which has every UID of the specification including the SOP Classes that aren't storage
+// classes. The padding of UID values from a dataset is ignored.
+//
+// The registry built into the github.com/gradienthealth/dicom/dicomuid package is used instead if the
+// schema doesn't have a UID registry, such as for schemas crawled before it was recorded. It has the
+// names and types of the standard UIDs, but not their keywords.
+func (sd *SchemaDef) LookupUID(uid string) (UIDDef, bool) {
+	uid = strings.TrimRight(uid, "\x00 ")
+
+	if len(sd.UIDs) > 0 {
+		ud, ok := sd.UIDs[uid]
+		return ud, ok
+	}
+
+	return lookupBuiltinUID(uid)
+}
+
+// Look up a UID in the registry of the github.com/gradienthealth/dicom/dicomuid package
+func lookupBuiltinUID(uid string) (UIDDef, bool) {
+	info, err := dicomuid.Lookup(strings.TrimRight(uid, "\x00 "))
+	if err != nil {
+		return UIDDef{}, false
+	}
+
+	ud := UIDDef{Name: info.Name, Type: UIDType(info.Type), Retired: info.Status == "Retired"}
+	// The types are capitalized differently (e.g. "Well-known SOP instance")
+	for _, t := range uidTypes {
+		if strings.EqualFold(string(t), string(info.Type)) {
+			ud.Type = t
+		}
+	}
+
+	return ud, true
+}
@@ -0,0 +1,142 @@
+package dicom
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// A UIDRemapper provides the replacement UIDs of the "U" action of the confidentiality profiles,
+// where a UID always has the same replacement so that the references between the instances of a
+// batch (e.g. from an RT Structure Set or a Segmentation to the images) still resolve after
+// de-identification. Use the same remapper with DeidentifyOptions for all the datasets of a batch:
+//
+//	remapper := dicom.NewHashUIDRemapper(key)
+//	err := dicom.Deidentify(ds, schema, dicom.DeidentifyOptions{ReplaceUID: remapper.RemapUID})
+type UIDRemapper interface {
+	RemapUID(uid string) (string, error)
+}
+
+// HashUIDRemapper replaces UIDs with a keyed hash (HMAC-SHA256) of the UID, so the replacements
+// are the same across batches, and across processes, that use the same key without having to keep
+// any state. The UIDs can't be recovered from their replacements without the key.
+type HashUIDRemapper struct {
+	key []byte
+}
+
+// NewHashUIDRemapper provides a HashUIDRemapper with a secret key, which should be at least 32
+// random bytes and kept for as long as the replacements need to be consistent.
+func NewHashUIDRemapper(key []byte) *HashUIDRemapper {
+	return &HashUIDRemapper{key: append([]byte{}, key...)}
+}
+
+// RemapUID provides the replacement of a UID, which is a UUID derived UID (with the "2.25." root)
+// from the keyed hash of the UID.
+func (r *HashUIDRemapper) RemapUID(uid string) (string, error) {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(strings.TrimRight(uid, "\x00 ")))
+	return uuidUID(mac.Sum(nil)[:16], 0x80), nil
+}
+
+// UIDTable replaces UIDs with new random UIDs and records the replacements, so they can be
+// persisted between batches with Write and ReadUIDTable. Unlike HashUIDRemapper, the table allows
+// the original UIDs to be found from their replacements, so it must be protected as carefully as
+// the original data. It is safe to use from multiple goroutines.
+type UIDTable struct {
+	mu   sync.Mutex
+	uids map[string]string
+}
+
+// NewUIDTable provides an empty UIDTable.
+func NewUIDTable() *UIDTable {
+	return &UIDTable{uids: map[string]string{}}
+}
+
+// ReadUIDTable reads a UIDTable written by Write, which is a JSON object of the original UIDs to
+// their replacements.
+func ReadUIDTable(r io.Reader) (*UIDTable, error) {
+	t := NewUIDTable()
+	if err := json.NewDecoder(r).Decode(&t.uids); err != nil {
+		return nil, fmt.Errorf("Unable to read the UID table: %v", err)
+	}
+	if t.uids == nil {
+		t.uids = map[string]string{}
+	}
+	return t, nil
+}
+
+// Write writes the replacements of the table as a JSON object of the original UIDs to their
+// replacements.
+func (t *UIDTable) Write(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, err := json.MarshalIndent(t.uids, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// RemapUID provides the replacement of a UID from the table, adding a new random UID (with the
+// "2.25." root) to the table if the UID doesn't have one yet.
+func (t *UIDTable) RemapUID(uid string) (string, error) {
+	uid = strings.TrimRight(uid, "\x00 ")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r, ok := t.uids[uid]; ok {
+		return r, nil
+	}
+
+	r, err := randomUID(uid)
+	if err != nil {
+		return "", err
+	}
+	t.uids[uid] = r
+
+	return r, nil
+}
+
+// Lookup provides the replacement of a UID if it is in the table.
+func (t *UIDTable) Lookup(uid string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.uids[strings.TrimRight(uid, "\x00 ")]
+	return r, ok
+}
+
+// Len provides the number of UIDs in the table.
+func (t *UIDTable) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.uids)
+}
+
+// A new UID from a random UUID, as described in PS3.5 Section B.2
+func randomUID(string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return uuidUID(b, 0x40), nil
+}
+
+// The UID of a UUID (see PS3.5 Section B.2) from 16 bytes with the version bits of the UUID (e.g.
+// 0x40 for version 4) and the variant bits of RFC 4122 set
+func uuidUID(b []byte, version byte) string {
+	u := append([]byte{}, b...)
+	u[6] = u[6]&0x0f | version
+	u[8] = u[8]&0x3f | 0x80
+	return "2.25." + new(big.Int).SetBytes(u).String()
+}
@@ -0,0 +1,159 @@
+package dicom
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+var testUIDKey = []byte("fedcba9876543210fedcba9876543210")
+
+// Check that a UID is a UUID derived UID (see PS3.5 Section B.2) of a UUID version
+func checkUUIDUID(t *testing.T, uid string, version byte) {
+	t.Helper()
+
+	digits := strings.TrimPrefix(uid, "2.25.")
+	n, ok := new(big.Int).SetString(digits, 10)
+	if !strings.HasPrefix(uid, "2.25.") || !ok || len(uid) > 64 || (len(digits) > 1 && digits[0] == '0') {
+		t.Errorf("UID %q is not a valid UUID derived UID", uid)
+		return
+	}
+
+	u := make([]byte, 16)
+	b := n.Bytes()
+	if len(b) > 16 {
+		t.Errorf("UID %q is larger than a UUID", uid)
+		return
+	}
+	copy(u[16-len(b):], b)
+	if u[6]&0xf0 != version || u[8]&0xc0 != 0x80 {
+		t.Errorf("UID %q has the UUID version %x and variant %x, want %x and 80", uid, u[6]&0xf0, u[8]&0xc0, version)
+	}
+}
+
+func TestHashUIDRemapper(t *testing.T) {
+	r := NewHashUIDRemapper(testUIDKey)
+
+	a, err := r.RemapUID("1.2.3.4.5")
+	if err != nil {
+		t.Fatalf("RemapUID error: %v", err)
+	}
+	checkUUIDUID(t, a, 0x80)
+
+	// The padding of the UID values doesn't change the replacement
+	if got, _ := r.RemapUID("1.2.3.4.5\x00"); got != a {
+		t.Errorf("RemapUID(%q) = %q, want %q", "1.2.3.4.5\x00", got, a)
+	}
+	// Another remapper with the same key gives the same replacement
+	if got, _ := NewHashUIDRemapper(testUIDKey).RemapUID("1.2.3.4.5"); got != a {
+		t.Errorf("RemapUID with the same key = %q, want %q", got, a)
+	}
+	if got, _ := r.RemapUID("1.2.3.4.6"); got == a {
+		t.Errorf("RemapUID of another UID = %q, want another replacement", got)
+	}
+	if got, _ := NewHashUIDRemapper(testPseudonymKey).RemapUID("1.2.3.4.5"); got == a {
+		t.Errorf("RemapUID with another key = %q, want another replacement", got)
+	}
+}
+
+func TestUIDTable(t *testing.T) {
+	table := NewUIDTable()
+
+	a, err := table.RemapUID("1.2.3.4.5 ")
+	if err != nil {
+		t.Fatalf("RemapUID error: %v", err)
+	}
+	checkUUIDUID(t, a, 0x40)
+
+	if got, _ := table.RemapUID("1.2.3.4.5"); got != a {
+		t.Errorf("RemapUID(%q) = %q, want %q", "1.2.3.4.5", got, a)
+	}
+	if got, _ := table.RemapUID("1.2.3.4.6"); got == a {
+		t.Errorf("RemapUID of another UID = %q, want another replacement", got)
+	}
+	if r, ok := table.Lookup("1.2.3.4.5\x00"); !ok || r != a {
+		t.Errorf("Lookup(%q) = %q, %v, want %q", "1.2.3.4.5\x00", r, ok, a)
+	}
+	if r, ok := table.Lookup("1.2.3.4.7"); ok {
+		t.Errorf("Lookup(%q) = %q, want no replacement", "1.2.3.4.7", r)
+	}
+	if table.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", table.Len())
+	}
+
+	// The replacements are kept when the table is written and read again
+	buf := &bytes.Buffer{}
+	if err := table.Write(buf); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	read, err := ReadUIDTable(buf)
+	if err != nil {
+		t.Fatalf("ReadUIDTable error: %v", err)
+	}
+	if got, _ := read.RemapUID("1.2.3.4.5"); got != a || read.Len() != 2 {
+		t.Errorf("RemapUID after ReadUIDTable = %q with %d UIDs, want %q with 2", got, read.Len(), a)
+	}
+}
+
+func TestReadUIDTable(t *testing.T) {
+	if _, err := ReadUIDTable(strings.NewReader(`["1.2.3"]`)); err == nil {
+		t.Errorf("ReadUIDTable of a JSON array succeeded, want an error")
+	}
+
+	table, err := ReadUIDTable(strings.NewReader("null"))
+	if err != nil {
+		t.Fatalf("ReadUIDTable error: %v", err)
+	}
+	if _, err := table.RemapUID("1.2.3.4.5"); err != nil || table.Len() != 1 {
+		t.Errorf("RemapUID of an empty table = %v with %d UIDs, want 1", err, table.Len())
+	}
+}
+
+func TestUIDTableConcurrent(t *testing.T) {
+	table := NewUIDTable()
+
+	results := make([]string, 8)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = table.RemapUID("1.2.3.4.5")
+		}(i)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if r != results[0] {
+			t.Errorf("RemapUID from several goroutines = %v, want the same replacement", results)
+			break
+		}
+	}
+}
+
+func TestDeidentifyUIDRemapper(t *testing.T) {
+	// The references between the datasets of a batch still resolve with the same remapper
+	for _, r := range []UIDRemapper{NewHashUIDRemapper(testUIDKey), NewUIDTable()} {
+		uids := []string{}
+		for i := 0; i < 2; i++ {
+			ds := testDeidentifyDataSet()
+			if err := Deidentify(ds, testDeidentifySchema(), DeidentifyOptions{ReplaceUID: r.RemapUID}); err != nil {
+				t.Fatalf("Deidentify error: %v", err)
+			}
+			e, err := ds.FindElementByTag(dicomtag.FrameOfReferenceUID)
+			if err != nil {
+				t.Fatalf("Deidentify removed FrameOfReferenceUID")
+			}
+			uid, _ := e.GetString()
+			uids = append(uids, uid)
+		}
+
+		if want, _ := r.RemapUID("1.2.840.10008.1.4.1.1"); uids[0] != want || uids[1] != want {
+			t.Errorf("%T FrameOfReferenceUID = %v, want %q in both datasets", r, uids, want)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package dicom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// IssueSeverity determines whether a validation issue makes the instance invalid.
+type IssueSeverity string
+
+const (
+	// SeverityError is an issue that makes the instance non-conformant.
+	SeverityError IssueSeverity = "error"
+	// SeverityWarning is an issue that doesn't make the instance non-conformant, but is worth reporting.
+	SeverityWarning IssueSeverity = "warning"
+)
+
+// IssueKind classifies a validation issue.
+type IssueKind string
+
+const (
+	// IssueUnknownSOPClass means that the SOPClassUID is missing or not defined in the schema.
+	IssueUnknownSOPClass IssueKind = "UnknownSOPClass"
+	// IssueMissingModule means that none of the attributes of a mandatory module are present.
+	IssueMissingModule IssueKind = "MissingModule"
+	// IssueMissingAttribute means that a Type 1 or Type 2 attribute is not present.
+	IssueMissingAttribute IssueKind = "MissingAttribute"
+	// IssueEmptyAttribute means that a Type 1 (or 1C) attribute is present, but has no value.
+	IssueEmptyAttribute IssueKind = "EmptyAttribute"
+	// IssueInvalidVR means that the VR of an element isn't one of the VRs in its tag definition.
+	IssueInvalidVR IssueKind = "InvalidVR"
+	// IssueInvalidVM means that the number of values of an element isn't allowed by its tag definition.
+	IssueInvalidVM IssueKind = "InvalidVM"
+	// IssueInvalidEnumeratedValue means that a value of an element isn't one of the Enumerated Values
+	// of its tag usage.
+	IssueInvalidEnumeratedValue IssueKind = "InvalidEnumeratedValue"
+	// IssueUnknownAttribute means that an element has a standard tag that isn't in the data dictionary.
+	IssueUnknownAttribute IssueKind = "UnknownAttribute"
+	// IssueInvalidTransferSyntax means that the Transfer Syntax UID is missing, unknown or not valid for the SOP Class.
+	IssueInvalidTransferSyntax IssueKind = "InvalidTransferSyntax"
+	// IssueRetiredTransferSyntax means that the Transfer Syntax UID is retired.
+	IssueRetiredTransferSyntax IssueKind = "RetiredTransferSyntax"
+	// IssueLossyTransferSyntax means that the transfer syntax is lossy, but lossy compression isn't allowed for
+	// the SOP Class by the TransferSyntaxRules.
+	IssueLossyTransferSyntax IssueKind = "LossyTransferSyntax"
+	// IssuePixelEncoding means that the Pixel Data, or the attributes that describe its compression, don't
+	// agree with the transfer syntax.
+	IssuePixelEncoding IssueKind = "PixelEncoding"
+	// IssueInvalidFileMeta means that the preamble, DICM prefix or File Meta Information of a file is malformed.
+	IssueInvalidFileMeta IssueKind = "InvalidFileMeta"
+)
+
+// A ValidationIssue describes a single problem found in a DICOM instance.
+type ValidationIssue struct {
+	Severity IssueSeverity
+	Kind     IssueKind
+	// Module is the name of the module in which the issue was found, if any.
+	Module string
+	// Path is the path of tags (e.g. ["(0040,0555)","(0040,a040)"]) to the attribute, if any.
+	Path    []string
+	Message string
+}
+
+func (vi ValidationIssue) String() string {
+	s := fmt.Sprintf("%s %s", vi.Severity, vi.Kind)
+	if vi.Module != "" {
+		s += fmt.Sprintf(" [%s]", vi.Module)
+	}
+	if len(vi.Path) > 0 {
+		s += " " + strings.Join(vi.Path, ">")
+	}
+	return s + ": " + vi.Message
+}
+
+// A ValidationReport is the result of validating a DICOM instance against a schema.
+type ValidationReport struct {
+	SOPClassUID string
+	// ClassName is the name of the SOP Class definition that was used, if one was found.
+	ClassName string
+	Issues    []ValidationIssue
+}
+
+// Valid reports whether there are no issues with error severity.
+func (r ValidationReport) Valid() bool {
+	return len(r.Errors()) == 0
+}
+
+// Errors provides only the issues with error severity.
+func (r ValidationReport) Errors() []ValidationIssue {
+	errs := []ValidationIssue{}
+	for _, vi := range r.Issues {
+		if vi.Severity == SeverityError {
+			errs = append(errs, vi)
+		}
+	}
+	return errs
+}
+
+func (r *ValidationReport) add(severity IssueSeverity, kind IssueKind, module string, path []string, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Severity: severity,
+		Kind:     kind,
+		Module:   module,
+		Path:     append([]string{}, path...),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Validate checks a DICOM dataset against the IOD of its SOP Class in the provided schema. The
+// SOP Class definition is found from the SOPClassUID of the dataset. Each mandatory ("M") module
+// must be present, which means that at least one of its top-level attributes is in the dataset.
+// Modules that are user optional ("U") or conditional ("C") are only checked if they are present.
+//
+// For the attributes of each checked module:
+//
+//	Type 1 attributes must be present with a value.
+//	Type 2 attributes must be present, but may be empty.
+//	Type 1C attributes must have a value if present. Their conditions are not evaluated.
+//	Type 2C and 3 attributes are only checked for their VR and VM if present.
+//
+// Attributes nested in sequences are checked for each item of the sequence when the sequence
+// is present. Attributes of repeating groups (e.g. "(60xx,3000)" for overlays) are checked for
+// each group in the dataset, but they are not reported as missing. The VR of every matching
+// element is checked against the TagDef.VR and the number of values against the TagDef.VM.
+// Values must be one of the Enumerated Values of the tag usage when the module lists them, while
+// Defined Terms are not checked since they may be extended.
+//
+// Every element, including those in sequence items, is also looked up in the data dictionary of
+// the schema. Tags that aren't in the dictionary, which leaves out the retired tags, are reported as warnings.
+// Private tags, group lengths and the file meta information are not looked up.
+//
+// The lookups use the SchemaIndex of the schema, which is the shared index when the schema was
+// provided by LoadSchema. Use SchemaIndex.Validate to validate with an index of another schema.
+func Validate(ds *dicom.DataSet, schema *SchemaDef) ValidationReport {
+	return schemaIndex(schema).Validate(ds)
+}
+
+// Validate checks a DICOM dataset against the IOD of its SOP Class in the indexed schema, see
+// Validate.
+func (si *SchemaIndex) Validate(ds *dicom.DataSet) ValidationReport {
+	report := ValidationReport{}
+
+	sce, err := ds.FindElementByTag(dicomtag.SOPClassUID)
+	if err != nil {
+		report.add(SeverityError, IssueUnknownSOPClass, "", []string{dicomtag.SOPClassUID.String()}, "SOPClassUID is not present")
+		return report
+	}
+	report.SOPClassUID, _ = sce.GetString()
+
+	cd, ok := si.ClassByUID(report.SOPClassUID)
+	if !ok {
+		report.add(SeverityError, IssueUnknownSOPClass, "", []string{dicomtag.SOPClassUID.String()}, "SOP Class %q is not defined in the schema", report.SOPClassUID)
+		return report
+	}
+	report.ClassName = cd.Name
+
+	v := validator{index: si, report: &report, checked: map[*dicom.Element]bool{}}
+
+	for _, mu := range cd.Modules {
+		md, ok := si.ModuleDefs[mu.Name]
+		if !ok {
+			continue
+		}
+
+		if !modulePresent(ds.Elements, md) {
+			if mu.Usage == "M" {
+				report.add(SeverityError, IssueMissingModule, mu.Name, nil, "Mandatory module %s is not present", mu.Name)
+			}
+			continue
+		}
+
+		for _, tu := range md.Tags {
+			v.checkUsage(mu.Name, tu, ds.Elements, 0)
+		}
+	}
+
+	v.checkDictionary(ds.Elements, nil)
+
+	return report
+}
+
+type validator struct {
+	index  *SchemaIndex
+	report *ValidationReport
+	// Elements can be matched by usages in more than one module, only check their values once
+	checked map[*dicom.Element]bool
+}
+
+// Check a tag usage relative to the elements of a dataset or sequence item, where depth is the
+// index in the tag usage path of the tag that should be found in the elements.
+func (v *validator) checkUsage(module string, tu TagUsage, elems []*dicom.Element, depth int) {
+	p, err := ParseTagPattern(tu.Path[depth])
+	if err != nil {
+		return
+	}
+
+	// Any number of the repeating groups (e.g. overlays) may be present, check the ones that are
+	if p.IsRepeating() {
+		for _, e := range elems {
+			if p.Match(e.Tag) {
+				v.checkFound(module, tu, e, depth)
+			}
+		}
+		return
+	}
+
+	t := dicomtag.Tag{Group: p.Group, Element: p.Element}
+	e, err := dicom.FindElementByTag(elems, t)
+
+	if err != nil {
+		// Parent sequences that are not present are checked by their own tag usage, and like the
+		// repeating patterns the groups of an expanded repeating group are only checked if present
+		if expandedGroup(t) {
+			return
+		}
+		if typ := strings.TrimSpace(tu.Type); depth == len(tu.Path)-1 && (typ == "1" || typ == "2") {
+			v.report.add(SeverityError, IssueMissingAttribute, module, tu.Path, "Type %s attribute %s is not present", typ, v.keyword(t))
+		}
+		return
+	}
+
+	v.checkFound(module, tu, e, depth)
+}
+
+// Check an element that was found for the tag at the depth of a tag usage path
+func (v *validator) checkFound(module string, tu TagUsage, e *dicom.Element, depth int) {
+	if depth < len(tu.Path)-1 {
+		for _, item := range e.Value {
+			if ie, ok := item.(*dicom.Element); ok {
+				v.checkUsage(module, tu, itemElements(ie), depth+1)
+			}
+		}
+		return
+	}
+
+	typ := strings.TrimSpace(tu.Type)
+	path := tu.Path
+
+	if (typ == "1" || typ == "1C") && elementEmpty(e) {
+		v.report.add(SeverityError, IssueEmptyAttribute, module, path, "Type %s attribute %s has no value", typ, v.keyword(e.Tag))
+	}
+
+	v.checkEnumeratedValues(module, tu, e)
+	v.checkElement(module, path, e)
+}
+
+// Check the values of an element against the Enumerated Values of a tag usage. Terms with a
+// value number only apply to that value of a multi-valued element.
+func (v *validator) checkEnumeratedValues(module string, tu TagUsage, e *dicom.Element) {
+	if len(tu.EnumeratedValues) == 0 {
+		return
+	}
+
+	for i, ev := range e.Value {
+		s, ok := ev.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			continue
+		}
+		s = strings.TrimSpace(s)
+
+		listed, allowed := false, false
+		for _, term := range tu.EnumeratedValues {
+			if term.ValueNumber != 0 && term.ValueNumber != i+1 {
+				continue
+			}
+			listed = true
+			if term.Value == s {
+				allowed = true
+				break
+			}
+		}
+
+		if listed && !allowed {
+			v.report.add(SeverityError, IssueInvalidEnumeratedValue, module, tu.Path, "%s value %d %q is not one of the Enumerated Values", v.keyword(e.Tag), i+1, s)
+		}
+	}
+}
+
+func (v *validator) checkElement(module string, path []string, e *dicom.Element) {
+	if v.checked[e] {
+		return
+	}
+	v.checked[e] = true
+
+	td, ok := v.index.TagByTag(e.Tag)
+	if !ok {
+		return
+	}
+
+	if len(td.VR) > 0 && td.VR[0] != "" && e.VR != "" {
+		found := false
+		for _, evr := range strings.Split(e.VR, " or ") {
+			for _, vr := range td.VR {
+				if evr == vr {
+					found = true
+				}
+			}
+		}
+		if !found {
+			v.report.add(SeverityError, IssueInvalidVR, module, path, "%s has VR %s, expected %s", td.Keyword, e.VR, strings.Join(td.VR, " or "))
+		}
+	}
+
+	switch dicomtag.GetVRKind(e.Tag, e.VR) {
+	case dicomtag.VRSequence, dicomtag.VRItem, dicomtag.VRPixelData, dicomtag.VRBytes, dicomtag.VRString:
+		// These always have a single value or items that are not counted by the VM
+		return
+	}
+
+	n := valueCount(e)
+	if n > 0 && !td.VM.Allows(n) {
+		v.report.add(SeverityError, IssueInvalidVM, module, path, "%s has %d values, expected VM %s", td.Keyword, n, td.VM)
+	}
+}
+
+// Look up the elements, and the elements of their sequence items, in the data dictionary
+func (v *validator) checkDictionary(elems []*dicom.Element, path []string) {
+	for _, e := range elems {
+		if e.Tag.Group%2 == 1 || e.Tag.Element == 0 || e.Tag.Group == dicomtag.MetadataGroup {
+			continue
+		}
+
+		epath := append(append([]string{}, path...), e.Tag.String())
+
+		if _, ok := v.index.TagByTag(e.Tag); !ok {
+			v.report.add(SeverityWarning, IssueUnknownAttribute, "", epath, "%s is not in the data dictionary", e.Tag)
+		}
+
+		for _, item := range e.Value {
+			if ie, ok := item.(*dicom.Element); ok && ie.Tag == dicomtag.Item {
+				v.checkDictionary(itemElements(ie), epath)
+			}
+		}
+	}
+}
+
+func (v *validator) keyword(t dicomtag.Tag) string {
+	if td, ok := v.index.TagByTag(t); ok && td.Keyword != "" {
+		return td.Keyword
+	}
+	return t.String()
+}
+
+// A module is considered present if any of its top-level attributes are in the dataset.
+func modulePresent(elems []*dicom.Element, md ModuleDef) bool {
+	for _, tu := range md.Tags {
+		if len(tu.Path) != 1 {
+			continue
+		}
+		p, err := ParseTagPattern(tu.Path[0])
+		if err != nil {
+			continue
+		}
+		for _, e := range elems {
+			if p.Match(e.Tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The elements contained in an item of a sequence.
+func itemElements(item *dicom.Element) []*dicom.Element {
+	elems := []*dicom.Element{}
+	for _, v := range item.Value {
+		if e, ok := v.(*dicom.Element); ok {
+			elems = append(elems, e)
+		}
+	}
+	return elems
+}
+
+func elementEmpty(e *dicom.Element) bool {
+	for _, v := range e.Value {
+		if s, ok := v.(string); !ok || strings.TrimSpace(s) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// The number of values in an element. Dates are provided by the parser as a single string
+// so they are split here.
+func valueCount(e *dicom.Element) int {
+	n := 0
+	for _, v := range e.Value {
+		if s, ok := v.(string); ok && e.VR == "DA" {
+			n += strings.Count(s, "\\") + 1
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,260 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/gradienthealth/dicom"
+	"github.com/gradienthealth/dicom/dicomtag"
+)
+
+// A schema with one IOD of a mandatory module with a sequence, a user optional module with a
+// repeating group and a mandatory module that is checked for presence
+func testValidateSchema() *SchemaDef {
+	return &SchemaDef{
+		ClassDefs: []ClassDef{{SOPClassUid: "1.2.3.4", Name: "Test Image Storage", Modules: []ModuleUsage{
+			{Name: "Patient", Usage: "M"},
+			{Name: "Overlay", Usage: "U"},
+			{Name: "Equipment", Usage: "M"},
+		}}},
+		ModuleDefs: map[string]ModuleDef{
+			"Patient": {Tags: []TagUsage{
+				{Path: []string{"(0008,0016)"}, Type: "1"},
+				{Path: []string{"(0010,0010)"}, Type: "2"},
+				{Path: []string{"(0010,0020)"}, Type: "1"},
+				{Path: []string{"(0028,0010)"}, Type: "3"},
+				{Path: []string{"(0008,1140)"}, Type: "3"},
+				{Path: []string{"(0008,1140)", "(0008,1150)"}, Type: "1"},
+			}},
+			"Overlay":   {Tags: []TagUsage{{Path: []string{"(60xx,0010)"}, Type: "1"}}},
+			"Equipment": {Tags: []TagUsage{{Path: []string{"(0008,0070)"}, Type: "2"}}},
+		},
+		TagDefs: map[string]TagDef{
+			"(0008,0016)": {Keyword: "SOPClassUID", VR: []string{"UI"}, VM: MustParseVM("1")},
+			"(0010,0010)": {Keyword: "PatientName", VR: []string{"PN"}, VM: MustParseVM("1")},
+			"(0010,0020)": {Keyword: "PatientID", VR: []string{"LO"}, VM: MustParseVM("1")},
+			"(0028,0010)": {Keyword: "Rows", VR: []string{"US"}, VM: MustParseVM("1")},
+			"(0008,1140)": {Keyword: "ReferencedImageSequence", VR: []string{"SQ"}, VM: MustParseVM("1")},
+			"(0008,1150)": {Keyword: "ReferencedSOPClassUID", VR: []string{"UI"}, VM: MustParseVM("1")},
+			"(60xx,0010)": {Keyword: "OverlayRows", VR: []string{"US"}, VM: MustParseVM("1")},
+			"(0008,0070)": {Keyword: "Manufacturer", VR: []string{"LO"}, VM: MustParseVM("1")},
+		},
+	}
+}
+
+func testValidateElement(t dicomtag.Tag, vr string, values ...interface{}) *dicom.Element {
+	return &dicom.Element{Tag: t, VR: vr, Value: values}
+}
+
+func TestValidate(t *testing.T) {
+	el := testValidateElement
+	sopClass := el(dicomtag.SOPClassUID, "UI", "1.2.3.4")
+	item := func(elems ...*dicom.Element) *dicom.Element {
+		values := make([]interface{}, len(elems))
+		for i, e := range elems {
+			values[i] = e
+		}
+		return &dicom.Element{Tag: dicomtag.Item, VR: "NA", Value: values}
+	}
+
+	tests := []struct {
+		name  string
+		elems []*dicom.Element
+		// The kinds of the issues that are expected, in order
+		want []IssueKind
+	}{
+		{"valid", []*dicom.Element{
+			sopClass,
+			el(dicomtag.PatientName, "PN", "Doe^John"),
+			el(dicomtag.PatientID, "LO", "12345"),
+			el(dicomtag.ReferencedImageSequence, "SQ", item(el(dicomtag.ReferencedSOPClassUID, "UI", "1.2.3.4"))),
+			el(dicomtag.Tag{Group: 0x6002, Element: 0x0010}, "US", uint16(512)),
+			el(dicomtag.Manufacturer, "LO"),
+		}, nil},
+		{"missing mandatory module", []*dicom.Element{
+			sopClass,
+			el(dicomtag.PatientName, "PN", "Doe^John"),
+			el(dicomtag.PatientID, "LO", "12345"),
+		}, []IssueKind{IssueMissingModule}},
+		{"missing and empty attributes", []*dicom.Element{
+			sopClass,
+			el(dicomtag.PatientID, "LO", ""),
+			el(dicomtag.Manufacturer, "LO"),
+		}, []IssueKind{IssueMissingAttribute, IssueEmptyAttribute}},
+		{"VR and VM", []*dicom.Element{
+			sopClass,
+			el(dicomtag.PatientName, "LO", "Doe^John"),
+			el(dicomtag.PatientID, "LO", "12345"),
+			el(dicomtag.Rows, "US", uint16(512), uint16(512)),
+			el(dicomtag.Manufacturer, "LO"),
+		}, []IssueKind{IssueInvalidVR, IssueInvalidVM}},
+		{"sequence item", []*dicom.Element{
+			sopClass,
+			el(dicomtag.PatientName, "PN", "Doe^John"),
+			el(dicomtag.PatientID, "LO", "12345"),
+			el(dicomtag.ReferencedImageSequence, "SQ", item(), item(el(dicomtag.ReferencedSOPClassUID, "UI", ""))),
+			el(dicomtag.Manufacturer, "LO"),
+		}, []IssueKind{IssueMissingAttribute, IssueEmptyAttribute}},
+		{"repeating group", []*dicom.Element{
+			sopClass,
+			el(dicomtag.PatientName, "PN", "Doe^John"),
+			el(dicomtag.PatientID, "LO", "12345"),
+			el(dicomtag.Tag{Group: 0x6000, Element: 0x0010}, "US", uint16(512)),
+			el(dicomtag.Tag{Group: 0x6004, Element: 0x0010}, "US", uint16(512), uint16(512)),
+			el(dicomtag.Manufacturer, "LO"),
+		}, []IssueKind{IssueInvalidVM}},
+		{"unknown attribute", []*dicom.Element{
+			sopClass,
+			el(dicomtag.PatientName, "PN", "Doe^John"),
+			el(dicomtag.PatientID, "LO", "12345"),
+			el(dicomtag.Manufacturer, "LO"),
+			el(dicomtag.PatientSex, "CS", "M"),
+			// Private tags and the file meta information are not looked up
+			el(dicomtag.Tag{Group: 0x0009, Element: 0x0010}, "LO", "PRIVATE"),
+			el(dicomtag.TransferSyntaxUID, "UI", "1.2.840.10008.1.2.1"),
+		}, []IssueKind{IssueUnknownAttribute}},
+		{"unknown SOP Class", []*dicom.Element{el(dicomtag.SOPClassUID, "UI", "1.2.3.5")}, []IssueKind{IssueUnknownSOPClass}},
+		{"no SOP Class", []*dicom.Element{el(dicomtag.PatientID, "LO", "12345")}, []IssueKind{IssueUnknownSOPClass}},
+	}
+
+	si := NewSchemaIndex(testValidateSchema())
+	for _, tt := range tests {
+		report := si.Validate(&dicom.DataSet{Elements: tt.elems})
+
+		kinds := []IssueKind{}
+		for _, vi := range report.Issues {
+			kinds = append(kinds, vi.Kind)
+		}
+		if len(kinds) != len(tt.want) {
+			t.Errorf("%s: Validate issues = %v, want %v", tt.name, report.Issues, tt.want)
+			continue
+		}
+		for i := range kinds {
+			if kinds[i] != tt.want[i] {
+				t.Errorf("%s: Validate issues = %v, want %v", tt.name, report.Issues, tt.want)
+				break
+			}
+		}
+		if valid := len(report.Errors()) == 0; report.Valid() != valid {
+			t.Errorf("%s: Valid() = %v with %d errors", tt.name, report.Valid(), len(report.Errors()))
+		}
+	}
+}
+
+func TestValidateExpandedGroups(t *testing.T) {
+	// The bundled data packages have a tag for each of the overlay groups instead of the pattern
+	schema := testValidateSchema()
+	delete(schema.TagDefs, "(60xx,0010)")
+	overlay := ModuleDef{}
+	for g := uint16(0x6000); g <= 0x6003; g++ {
+		key := dicomtag.Tag{Group: g, Element: 0x0010}.String()
+		schema.TagDefs[key] = TagDef{Keyword: "OverlayRows", VR: []string{"US"}, VM: MustParseVM("1")}
+		overlay.Tags = append(overlay.Tags, TagUsage{Path: []string{key}, Type: "1"})
+	}
+	schema.ModuleDefs["Overlay"] = overlay
+
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		testValidateElement(dicomtag.SOPClassUID, "UI", "1.2.3.4"),
+		testValidateElement(dicomtag.PatientName, "PN", "Doe^John"),
+		testValidateElement(dicomtag.PatientID, "LO", "12345"),
+		testValidateElement(dicomtag.Tag{Group: 0x6002, Element: 0x0010}, "US", uint16(512), uint16(512)),
+		testValidateElement(dicomtag.Manufacturer, "LO"),
+	}}
+	report := Validate(ds, schema)
+
+	// Only the group that is present is checked
+	if len(report.Issues) != 1 || report.Issues[0].Kind != IssueInvalidVM {
+		t.Errorf("Validate issues = %v, want one %s", report.Issues, IssueInvalidVM)
+	}
+}
+
+func TestValidateReport(t *testing.T) {
+	ds := &dicom.DataSet{Elements: []*dicom.Element{
+		testValidateElement(dicomtag.SOPClassUID, "UI", "1.2.3.4"),
+		testValidateElement(dicomtag.PatientID, "LO", "12345"),
+	}}
+	report := Validate(ds, testValidateSchema())
+
+	if report.SOPClassUID != "1.2.3.4" || report.ClassName != "Test Image Storage" {
+		t.Errorf("Validate SOP Class = %q, %q, want 1.2.3.4, Test Image Storage", report.SOPClassUID, report.ClassName)
+	}
+	if report.Valid() {
+		t.Errorf("Validate of a dataset without PatientName and Equipment is valid")
+	}
+	for _, vi := range report.Errors() {
+		if vi.Severity != SeverityError {
+			t.Errorf("Errors() has %v", vi)
+		}
+	}
+}
+
+func TestValidateLoadedSchema(t *testing.T) {
+	sch, err := LoadSchema("test-load")
+	if err != nil {
+		t.Fatalf("LoadSchema error: %v", err)
+	}
+	si, err := LoadSchemaIndex("test-load")
+	if err != nil {
+		t.Fatalf("LoadSchemaIndex error: %v", err)
+	}
+	// The shared index of the loaded schema is used
+	if schemaIndex(sch) != si {
+		t.Errorf("schemaIndex of a loaded schema is not the index of LoadSchemaIndex")
+	}
+
+	ds := &dicom.DataSet{Elements: []*dicom.Element{testValidateElement(dicomtag.SOPClassUID, "UI", "1.2.3.4")}}
+	if report := Validate(ds, sch); len(report.Errors()) != 1 || report.Errors()[0].Kind != IssueMissingModule {
+		t.Errorf("Validate errors = %v, want a MissingModule", report.Errors())
+	}
+}
+
+func TestValidateEnumeratedValues(t *testing.T) {
+	schema := &SchemaDef{
+		ClassDefs: []ClassDef{{SOPClassUid: "1.2.3.4", Name: "Test Image Storage", Modules: []ModuleUsage{{Name: "Image", Usage: "M"}}}},
+		ModuleDefs: map[string]ModuleDef{
+			"Image": {Tags: []TagUsage{
+				{Path: []string{"(0010,0040)"}, Type: "2", EnumeratedValues: []Term{{Value: "M"}, {Value: "F"}, {Value: "O"}}},
+				// The terms of the first value only, the other values can be anything
+				{Path: []string{"(0008,0008)"}, Type: "1", EnumeratedValues: []Term{{Value: "ORIGINAL", ValueNumber: 1}, {Value: "DERIVED", ValueNumber: 1}}},
+				// Defined Terms may be extended
+				{Path: []string{"(0008,0060)"}, Type: "1", DefinedTerms: []Term{{Value: "CT"}, {Value: "MR"}}},
+			}},
+		},
+		TagDefs: map[string]TagDef{
+			"(0010,0040)": {Keyword: "PatientSex", VR: []string{"CS"}, VM: MustParseVM("1")},
+			"(0008,0008)": {Keyword: "ImageType", VR: []string{"CS"}, VM: MustParseVM("2-n")},
+			"(0008,0060)": {Keyword: "Modality", VR: []string{"CS"}, VM: MustParseVM("1")},
+		},
+	}
+	el := testValidateElement
+
+	tests := []struct {
+		name  string
+		elems []*dicom.Element
+		// The number of InvalidEnumeratedValue issues that are expected
+		want int
+	}{
+		{"valid", []*dicom.Element{el(dicomtag.PatientSex, "CS", "F"), el(dicomtag.ImageType, "CS", "ORIGINAL", "PRIMARY", "AXIAL"), el(dicomtag.Modality, "CS", "XA")}, 0},
+		// Empty values are checked by the Type and the padding is ignored
+		{"empty and padded", []*dicom.Element{el(dicomtag.PatientSex, "CS", ""), el(dicomtag.ImageType, "CS", "DERIVED ", "SECONDARY"), el(dicomtag.Modality, "CS", "CT")}, 0},
+		{"not enumerated", []*dicom.Element{el(dicomtag.PatientSex, "CS", "MALE"), el(dicomtag.ImageType, "CS", "ORIGINAL", "PRIMARY"), el(dicomtag.Modality, "CS", "CT")}, 1},
+		{"value number", []*dicom.Element{el(dicomtag.PatientSex, "CS", "M"), el(dicomtag.ImageType, "CS", "PRIMARY", "ORIGINAL"), el(dicomtag.Modality, "CS", "CT")}, 1},
+	}
+
+	for _, tt := range tests {
+		ds := &dicom.DataSet{Elements: append([]*dicom.Element{el(dicomtag.SOPClassUID, "UI", "1.2.3.4")}, tt.elems...)}
+		report := Validate(ds, schema)
+
+		n := 0
+		for _, vi := range report.Issues {
+			if vi.Kind == IssueInvalidEnumeratedValue {
+				n++
+				if vi.Severity != SeverityError {
+					t.Errorf("%s: %v is not an error", tt.name, vi)
+				}
+			}
+		}
+		if n != tt.want {
+			t.Errorf("%s: Validate issues = %v, want %d InvalidEnumeratedValue", tt.name, report.Issues, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package dicom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A VM (Value Multiplicity) is the parsed form of the multiplicities found in PS3.6, such as
+// "1", "1-3", "1-n", "2-2n" or "3-3n". The number of values n is allowed when it is at least Min,
+// at most Max (unless Unbounded) and when it is Min plus a multiple of Step. For example, "2-2n"
+// is Min 2, Step 2 and Unbounded, which allows 2, 4, 6...
+//
+// The zero VM is an unknown multiplicity (e.g. for the Item tags) and allows any number of values.
+type VM struct {
+	Min       int
+	Max       int
+	Step      int
+	Unbounded bool
+
+	// The ranges of a multiplicity with alternatives separated by "or", such as 2 and 6 for
+	// "2 or 6". Min, Max and Unbounded are then the bounds of all the alternatives.
+	Alternatives []VM
+
+	// The original form from the specification.
+	raw string
+}
+
+// ParseVM parses a value multiplicity from the specification. Alternatives separated by "or",
+// such as "2 or 6", are kept in the Alternatives of the VM, which allows the counts of any of them.
+func ParseVM(s string) (VM, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return VM{}, nil
+	}
+
+	var vm VM
+	alts := strings.Split(s, " or ")
+	for i, alt := range alts {
+		avm, err := parseVMRange(strings.TrimSpace(alt))
+		if err != nil {
+			return VM{}, fmt.Errorf("Invalid VM %q: %v", s, err)
+		}
+
+		if len(alts) > 1 {
+			vm.Alternatives = append(vm.Alternatives, avm)
+		}
+		if i == 0 {
+			vm.Min, vm.Max, vm.Step, vm.Unbounded = avm.Min, avm.Max, avm.Step, avm.Unbounded
+			continue
+		}
+
+		if avm.Min < vm.Min {
+			vm.Min = avm.Min
+		}
+		if avm.Max > vm.Max {
+			vm.Max = avm.Max
+		}
+		vm.Unbounded = vm.Unbounded || avm.Unbounded
+		if avm.Step != vm.Step {
+			vm.Step = 1
+		}
+	}
+	if vm.Unbounded {
+		vm.Max = 0
+	}
+
+	vm.raw = s
+	return vm, nil
+}
+
+// MustParseVM is like ParseVM, but panics on error.
+func MustParseVM(s string) VM {
+	vm, err := ParseVM(s)
+	if err != nil {
+		panic(err)
+	}
+	return vm
+}
+
+func parseVMRange(s string) (VM, error) {
+	bounds := strings.SplitN(s, "-", 2)
+
+	min, err := strconv.Atoi(bounds[0])
+	if err != nil || min < 0 {
+		return VM{}, fmt.Errorf("bad minimum %q", bounds[0])
+	}
+
+	if len(bounds) == 1 {
+		return VM{Min: min, Max: min, Step: 1}, nil
+	}
+
+	if strings.HasSuffix(bounds[1], "n") {
+		step := 1
+		if f := strings.TrimSuffix(bounds[1], "n"); f != "" {
+			step, err = strconv.Atoi(f)
+			if err != nil || step < 1 {
+				return VM{}, fmt.Errorf("bad step %q", bounds[1])
+			}
+		}
+		return VM{Min: min, Step: step, Unbounded: true}, nil
+	}
+
+	max, err := strconv.Atoi(bounds[1])
+	if err != nil || max < min {
+		return VM{}, fmt.Errorf("bad maximum %q", bounds[1])
+	}
+
+	return VM{Min: min, Max: max, Step: 1}, nil
+}
+
+// Allows reports whether n values are allowed by this multiplicity.
+func (vm VM) Allows(n int) bool {
+	if vm.Step == 0 {
+		return true
+	}
+	if len(vm.Alternatives) > 0 {
+		for _, avm := range vm.Alternatives {
+			if avm.Allows(n) {
+				return true
+			}
+		}
+		return false
+	}
+	if n < vm.Min || (!vm.Unbounded && n > vm.Max) {
+		return false
+	}
+	return (n-vm.Min)%vm.Step == 0
+}
+
+// Fixed provides the number of values when this multiplicity allows exactly one count (e.g. "1" or "3").
+func (vm VM) Fixed() (int, bool) {
+	if vm.Step == 0 || vm.Unbounded || vm.Min != vm.Max {
+		return 0, false
+	}
+	return vm.Min, true
+}
+
+// Multiple reports whether this multiplicity allows more than one value, which means that a
+// list is needed to hold the values.
+func (vm VM) Multiple() bool {
+	return vm.Unbounded || vm.Max > 1
+}
+
+// String provides the multiplicity in the form used in the specification.
+func (vm VM) String() string {
+	if vm.raw != "" || vm.Step == 0 {
+		return vm.raw
+	}
+
+	if vm.Unbounded {
+		if vm.Step == 1 {
+			return fmt.Sprintf("%d-n", vm.Min)
+		}
+		return fmt.Sprintf("%d-%dn", vm.Min, vm.Step)
+	}
+	if vm.Min == vm.Max {
+		return strconv.Itoa(vm.Min)
+	}
+	return fmt.Sprintf("%d-%d", vm.Min, vm.Max)
+}
+
+// MarshalJSON encodes the multiplicity in its specification form (e.g. "2-2n").
+func (vm VM) MarshalJSON() ([]byte, error) {
+	return json.Marshal(vm.String())
+}
+
+// UnmarshalJSON decodes the multiplicity from its specification form (e.g. "2-2n").
+func (vm *VM) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := ParseVM(s)
+	if err != nil {
+		return err
+	}
+
+	*vm = v
+	return nil
+}
@@ -0,0 +1,81 @@
+package dicom
+
+import "testing"
+
+func TestParseVM(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    VM
+		allowed []int
+		denied  []int
+	}{
+		{"", VM{}, []int{0, 1, 7}, nil},
+		{"1", VM{Min: 1, Max: 1, Step: 1}, []int{1}, []int{0, 2}},
+		{"1-3", VM{Min: 1, Max: 3, Step: 1}, []int{1, 2, 3}, []int{0, 4}},
+		{"1-n", VM{Min: 1, Step: 1, Unbounded: true}, []int{1, 2, 100}, []int{0}},
+		{"2-2n", VM{Min: 2, Step: 2, Unbounded: true}, []int{2, 4, 6}, []int{1, 3, 5}},
+		{"3-3n", VM{Min: 3, Step: 3, Unbounded: true}, []int{3, 6}, []int{4, 5}},
+		{"1-n or 1", VM{Min: 1, Step: 1, Unbounded: true}, []int{1, 2}, []int{0}},
+		// The alternatives are allowed, but not the counts between them
+		{"2 or 6", VM{Min: 2, Max: 6, Step: 1}, []int{2, 6}, []int{1, 3, 4, 5, 7}},
+		{"1-2 or 4-4n", VM{Min: 1, Step: 1, Unbounded: true}, []int{1, 2, 4, 8}, []int{0, 3, 5, 6}},
+	}
+
+	for _, tt := range tests {
+		vm, err := ParseVM(tt.in)
+		if err != nil {
+			t.Errorf("ParseVM(%q) error: %v", tt.in, err)
+			continue
+		}
+		if vm.Min != tt.want.Min || vm.Max != tt.want.Max || vm.Step != tt.want.Step || vm.Unbounded != tt.want.Unbounded {
+			t.Errorf("ParseVM(%q) = %+v, want %+v", tt.in, vm, tt.want)
+		}
+		if vm.String() != tt.in {
+			t.Errorf("ParseVM(%q).String() = %q", tt.in, vm.String())
+		}
+		for _, n := range tt.allowed {
+			if !vm.Allows(n) {
+				t.Errorf("ParseVM(%q).Allows(%d) = false, want true", tt.in, n)
+			}
+		}
+		for _, n := range tt.denied {
+			if vm.Allows(n) {
+				t.Errorf("ParseVM(%q).Allows(%d) = true, want false", tt.in, n)
+			}
+		}
+	}
+}
+
+func TestParseVMInvalid(t *testing.T) {
+	for _, in := range []string{"x", "1-", "3-1", "1-0n", "-1", "1-n or y"} {
+		if vm, err := ParseVM(in); err == nil {
+			t.Errorf("ParseVM(%q) = %+v, want an error", in, vm)
+		}
+	}
+}
+
+func TestVMFixed(t *testing.T) {
+	tests := []struct {
+		in    string
+		n     int
+		fixed bool
+		multi bool
+	}{
+		{"1", 1, true, false},
+		{"3", 3, true, true},
+		{"1-2", 0, false, true},
+		{"1-n", 0, false, true},
+		{"2 or 6", 0, false, true},
+		{"", 0, false, false},
+	}
+
+	for _, tt := range tests {
+		vm := MustParseVM(tt.in)
+		if n, fixed := vm.Fixed(); n != tt.n || fixed != tt.fixed {
+			t.Errorf("MustParseVM(%q).Fixed() = %d, %v, want %d, %v", tt.in, n, fixed, tt.n, tt.fixed)
+		}
+		if multi := vm.Multiple(); multi != tt.multi {
+			t.Errorf("MustParseVM(%q).Multiple() = %v, want %v", tt.in, multi, tt.multi)
+		}
+	}
+}